@@ -0,0 +1,331 @@
+// Package cfgranttag defines an Analyzer which reports problems of cfgrant struct tags:
+// malformed tags, unknown or duplicated options, default values which can't be converted into field type,
+// environment variables and command line arguments used by several fields of configuration and tags
+// on unexported fields, which are ignored on processing. Tags are parsed by the same parser as on processing
+package cfgranttag
+
+import (
+	"go/ast"
+	"go/types"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/umalmyha/configrant/internal/cfgargs"
+	"github.com/umalmyha/configrant/internal/structs"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check cfgrant struct tags
+
+The cfgranttag analyzer reports malformed cfgrant tags, unknown or duplicated options,
+default values which can't be converted into field type, environment variables,
+command line arguments and configuration file keys used by several fields of the same
+configuration and tags on unexported fields, which are ignored by configrant.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "cfgranttag",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nested := make(map[*types.Named]bool)
+	inspect.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(n ast.Node) {
+		st, ok := pass.TypesInfo.Types[n.(*ast.StructType)].Type.(*types.Struct)
+		if !ok {
+			return
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			checkField(pass, st.Field(i), st.Tag(i))
+			typ := deref(st.Field(i).Type())
+			switch t := typ.(type) {
+			case *types.Slice:
+				typ = deref(t.Elem())
+			case *types.Map:
+				typ = deref(t.Elem())
+			}
+			if named, ok := typ.(*types.Named); ok {
+				nested[named] = true
+			}
+		}
+	})
+
+	// names are compared within configuration tree, so only structs which aren't nested into others are walked
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		typeName, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+		named, ok := typeName.Type().(*types.Named)
+		if !ok || nested[named] {
+			continue
+		}
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			t := &tree{
+				pass:     pass,
+				envs:     make(map[string]string),
+				args:     make(map[string]string),
+				keys:     make(map[string]string),
+				visiting: make(map[*types.Struct]bool),
+			}
+			t.walk("", "", nil, st)
+		}
+	}
+	return nil, nil
+}
+
+func checkField(pass *analysis.Pass, field *types.Var, structTag string) {
+	value, ok := reflect.StructTag(structTag).Lookup("cfgrant")
+	if !ok || value == "-" {
+		return
+	}
+	if !field.Exported() {
+		pass.Reportf(field.Pos(), "cfgrant tag on unexported field %s is ignored", field.Name())
+		return
+	}
+	tag, err := structs.ParseTag(value)
+	if err != nil {
+		pass.Reportf(field.Pos(), "%v", err)
+		return
+	}
+	for _, problem := range tag.Problems {
+		pass.Reportf(field.Pos(), "%v", problem)
+	}
+	if tag.Default == "" {
+		return
+	}
+	if typ, ok := reflectType(deref(field.Type())); ok {
+		if err := tag.CheckDefault(typ); err != nil {
+			pass.Reportf(field.Pos(), "default value %q can't be used for field %s: %v", tag.Default, field.Name(), err)
+		}
+	}
+}
+
+// tree collects environment variables, command line arguments and configuration file keys of configuration tree
+// the same way Check does: elements of slices and maps of structs are walked with * standing for index or key
+type tree struct {
+	pass     *analysis.Pass
+	envs     map[string]string
+	args     map[string]string
+	keys     map[string]string
+	visiting map[*types.Struct]bool
+}
+
+// elementScope holds names of collection element, names of element fields are nested into them
+type elementScope struct {
+	env string
+	arg string
+}
+
+func (t *tree) walk(path string, keyPath string, scope *elementScope, st *types.Struct) {
+	if t.visiting[st] {
+		return
+	}
+	t.visiting[st] = true
+	defer delete(t.visiting, st)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		value := reflect.StructTag(st.Tag(i)).Get("cfgrant")
+		if !field.Exported() || value == "-" {
+			continue
+		}
+		tag, err := structs.ParseTag(value)
+		if err != nil {
+			continue
+		}
+		fieldPath := joinPath(path, field.Name())
+		key := tag.Key
+		if key == "" {
+			key = field.Name()
+		}
+		fieldKey := joinPath(keyPath, key)
+		env, arg, short, positional := tag.Env, tag.Arg, tag.Short, tag.Positional
+		if scope != nil {
+			env = scopedName(scope.env, "_", env)
+			arg = scopedName(scope.arg, ".", strings.TrimLeft(arg, "-"))
+			short, positional = "", false
+		}
+		typ := deref(field.Type())
+		if sub, ok := typ.Underlying().(*types.Struct); ok && !isDecodable(typ) {
+			t.walk(fieldPath, fieldKey, scope, sub)
+			continue
+		}
+		t.claim(t.envs, field, fieldPath, env, "environment variable")
+		if !positional {
+			t.claim(t.args, field, fieldPath, cfgargs.Normalize(arg), "command line argument")
+			t.claim(t.args, field, fieldPath, cfgargs.NormalizeShort(short), "command line argument")
+		}
+		t.claim(t.keys, field, fieldPath, strings.ToLower(fieldKey), "configuration file key")
+		if elem, ok := collectionElem(typ); ok {
+			elementScope := &elementScope{env: scopedName(env, "_", "*"), arg: scopedName(arg, ".", "*")}
+			t.walk(fieldPath+"[*]", joinPath(fieldKey, "*"), elementScope, elem)
+		}
+	}
+}
+
+// collectionElem returns struct of slice or map of structs elements, which are maintained field by field
+func collectionElem(typ types.Type) (*types.Struct, bool) {
+	var elem types.Type
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		elem = t.Elem()
+	case *types.Map:
+		elem = t.Elem()
+	default:
+		return nil, false
+	}
+	elem = deref(elem)
+	st, ok := elem.Underlying().(*types.Struct)
+	if !ok || isDecodable(elem) {
+		return nil, false
+	}
+	return st, true
+}
+
+func scopedName(scope string, sep string, name string) string {
+	if scope == "" || name == "" {
+		return ""
+	}
+	return scope + sep + name
+}
+
+func (t *tree) claim(claimed map[string]string, field *types.Var, path string, name string, kind string) {
+	if name == "" {
+		return
+	}
+	owner, ok := claimed[name]
+	if !ok {
+		claimed[name] = path
+		return
+	}
+	// fields of other packages are reported when those packages are analyzed
+	if field.Pkg() == t.pass.Pkg {
+		t.pass.Reportf(field.Pos(), "%s %s is already used by field %s", kind, name, owner)
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func deref(typ types.Type) types.Type {
+	for {
+		ptr, ok := typ.(*types.Pointer)
+		if !ok {
+			return typ
+		}
+		typ = ptr.Elem()
+	}
+}
+
+// configrantPath is import path of the package which declares Secret wrapper
+const configrantPath = "github.com/umalmyha/configrant"
+
+// knownTypes are named types which are converted from string by configrant itself
+var knownTypes = map[string]reflect.Type{
+	"time.Duration":  reflect.TypeOf(time.Duration(0)),
+	"time.Time":      reflect.TypeOf(time.Time{}),
+	"net/url.URL":    reflect.TypeOf(url.URL{}),
+	"net.IP":         reflect.TypeOf(net.IP{}),
+	"net.IPNet":      reflect.TypeOf(net.IPNet{}),
+	"regexp.Regexp":  reflect.TypeOf(regexp.Regexp{}),
+	"io/fs.FileMode": reflect.TypeOf(os.FileMode(0)),
+}
+
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:    reflect.TypeOf(false),
+	types.Int:     reflect.TypeOf(int(0)),
+	types.Int8:    reflect.TypeOf(int8(0)),
+	types.Int16:   reflect.TypeOf(int16(0)),
+	types.Int32:   reflect.TypeOf(int32(0)),
+	types.Int64:   reflect.TypeOf(int64(0)),
+	types.Uint:    reflect.TypeOf(uint(0)),
+	types.Uint8:   reflect.TypeOf(uint8(0)),
+	types.Uint16:  reflect.TypeOf(uint16(0)),
+	types.Uint32:  reflect.TypeOf(uint32(0)),
+	types.Uint64:  reflect.TypeOf(uint64(0)),
+	types.Float32: reflect.TypeOf(float32(0)),
+	types.Float64: reflect.TypeOf(float64(0)),
+	types.String:  reflect.TypeOf(""),
+}
+
+// reflectType returns runtime type equal to typ. Named types without decoding methods are converted like
+// their underlying type, other named types are decoded by their methods, so their default values aren't checked
+func reflectType(typ types.Type) (reflect.Type, bool) {
+	switch t := typ.(type) {
+	case *types.Basic:
+		rt, ok := basicTypes[t.Kind()]
+		return rt, ok
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			return nil, false
+		}
+		if t.Obj().Pkg().Path() == configrantPath && t.Obj().Name() == "Secret" && t.TypeArgs().Len() == 1 {
+			// secret wrapper is maintained like the wrapped type
+			return reflectType(t.TypeArgs().At(0))
+		}
+		if rt, ok := knownTypes[t.Obj().Pkg().Path()+"."+t.Obj().Name()]; ok {
+			return rt, true
+		}
+		if hasMethod(t, "Decode", "UnmarshalText", "UnmarshalBinary") {
+			return nil, false
+		}
+		return reflectType(t.Underlying())
+	case *types.Slice:
+		elem, ok := reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.SliceOf(elem), true
+	case *types.Array:
+		elem, ok := reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.ArrayOf(int(t.Len()), elem), true
+	case *types.Map:
+		key, ok := reflectType(t.Key())
+		if !ok {
+			return nil, false
+		}
+		elem, ok := reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.MapOf(key, elem), true
+	default:
+		return nil, false
+	}
+}
+
+// isDecodable reports whether struct type is converted from string as a whole, so it isn't walked into
+func isDecodable(typ types.Type) bool {
+	if _, ok := reflectType(typ); ok {
+		return true
+	}
+	return hasMethod(typ, "Decode", "UnmarshalText", "UnmarshalBinary", "SecretElem")
+}
+
+// hasMethod reports whether pointer to typ has any of named methods
+func hasMethod(typ types.Type, names ...string) bool {
+	methods := types.NewMethodSet(types.NewPointer(typ))
+	for _, name := range names {
+		if sel := methods.Lookup(nil, name); sel != nil {
+			return true
+		}
+	}
+	return false
+}
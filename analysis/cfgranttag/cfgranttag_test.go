@@ -0,0 +1,12 @@
+package cfgranttag_test
+
+import (
+	"testing"
+
+	"github.com/umalmyha/configrant/analysis/cfgranttag"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), cfgranttag.Analyzer, "a")
+}
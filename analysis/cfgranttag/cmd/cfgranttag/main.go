@@ -0,0 +1,13 @@
+// Command cfgranttag runs cfgranttag analyzer standalone or as a vet tool:
+//
+//	go vet -vettool=$(which cfgranttag) ./...
+package main
+
+import (
+	"github.com/umalmyha/configrant/analysis/cfgranttag"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(cfgranttag.Analyzer)
+}
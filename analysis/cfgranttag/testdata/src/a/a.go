@@ -0,0 +1,50 @@
+package a
+
+import (
+	"net"
+	"time"
+)
+
+type Database struct {
+	Host string `cfgrant:"env:DB_HOST,arg:host"`
+	Port int    `cfgrant:"env:PORT,default:5432"`
+}
+
+type Config struct {
+	DB       Database          `cfgrant:"key:db"`
+	Host     string            `cfgrant:"env:HOST,arg:host"`        // want `command line argument --host is already used by field DB.Host`
+	Port     int               `cfgrant:"env:PORT,default:8080"`    // want `environment variable PORT is already used by field DB.Port`
+	Timeout  time.Duration     `cfgrant:"default:5"`                // want `default value "5" can't be used for field Timeout: time: missing unit in duration "5"`
+	Retries  int               `cfgrant:"default:three"`            // want `default value "three" can't be used for field Retries`
+	IP       net.IP            `cfgrant:"default:localhost"`        // want `default value "localhost" can't be used for field IP`
+	Point    [2]int            `cfgrant:"default:1;2;3"`            // want `array \[2\]int requires exactly 2 elements`
+	Verbose  bool              `cfgrant:"env:VERBOSE,option:value"` // want `invalid cfgrant tag: unknown option option`
+	Name     string            `cfgrant:"env:NAME,env:TITLE"`       // want `invalid cfgrant tag: option env is specified more than once`
+	Quote    string            `cfgrant:"default:'unterminated"`    // want `invalid cfgrant tag: quote opened at position 8 is not closed`
+	Flag     bool              `cfgrant:"required:true"`            // want `invalid cfgrant tag: option required has no value`
+	Quiet    bool              `cfgrant:"arg:-q"`
+	Silent   bool              `cfgrant:"arg:silent,short:q"` // want `command line argument -q is already used by field Quiet`
+	Alias    string            `cfgrant:"key:port"`           // want `configuration file key port is already used by field Port`
+	Ups      []Upstream        `cfgrant:"env:UPS,arg:ups"`
+	secret   string            `cfgrant:"env:SECRET"` // want `cfgrant tag on unexported field secret is ignored`
+	Level    Level             `cfgrant:"default:anything"`
+	Listen   Port              `cfgrant:"default:http"` // want `default value "http" can't be used for field Listen`
+	Ports    []Port            `cfgrant:"default:80;443"`
+	Labels   map[string]string `cfgrant:"default:a:1;b:http://example.com"`
+	Ignored  string            `cfgrant:"-"`
+	Untagged string
+}
+
+type Upstream struct {
+	Host string `cfgrant:"env:HOST,arg:host"`
+	Addr string `cfgrant:"env:HOST,arg:addr"` // want `environment variable UPS_\*_HOST is already used by field Ups\[\*\].Host`
+	Name string `cfgrant:"arg:-host"`         // want `command line argument --ups.\*.host is already used by field Ups\[\*\].Host`
+}
+
+type Port int
+
+type Level int
+
+func (l *Level) Decode(value string) error {
+	return nil
+}
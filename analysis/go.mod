@@ -0,0 +1,15 @@
+module github.com/umalmyha/configrant/analysis
+
+go 1.22.0
+
+require (
+	github.com/umalmyha/configrant v0.0.0
+	golang.org/x/tools v0.26.0
+)
+
+require (
+	golang.org/x/mod v0.21.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+)
+
+replace github.com/umalmyha/configrant => ../
@@ -1,18 +1,400 @@
 package configrant
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/umalmyha/configrant/internal/cfgargs"
+	"github.com/umalmyha/configrant/internal/cfgfile"
 	"github.com/umalmyha/configrant/internal/structs"
 )
 
+const (
+	// ConfigFileArg is a command line argument which points to a configuration file
+	ConfigFileArg = "--config"
+	// ConfigFileEnv is an environment variable which points to a configuration file, used if ConfigFileArg is not passed
+	ConfigFileEnv = "CONFIG_FILE"
+	// EnvFileSuffix is appended to environment variable name to get variable which points to a file with the value
+	EnvFileSuffix = "_FILE"
+	// MaxValueFileSize limits size of files field values are read from
+	MaxValueFileSize = structs.MaxValueFileSize
+)
+
+// FieldError describes a field which value couldn't be applied: Go field path,
+// source of the raw value, the raw value itself and the underlying error
+type FieldError = structs.FieldError
+
+// Errors is returned by Process and lists every field which couldn't be maintained.
+// It supports errors.Is and errors.As, so underlying errors are reachable as well
+type Errors = structs.Errors
+
+// Decoder is implemented by types which decode themselves from raw string value.
+// Types implementing encoding.TextUnmarshaler or encoding.BinaryUnmarshaler are decoded
+// with corresponding method as well, Decoder takes precedence over them
+type Decoder = structs.Decoder
+
+var (
+	// ErrNotPtrStruct is returned when configuration is not a pointer to a struct
+	ErrNotPtrStruct = structs.ErrNotPtrStruct
+	// ErrRequired is reported for required fields which got no value from any source
+	ErrRequired = structs.ErrRequired
+	// ErrUnknownArg is returned in strict mode when command line argument doesn't belong to any field
+	ErrUnknownArg = cfgargs.ErrUnknownArg
+	// ErrMissingValue is returned when non-boolean command line argument is the last one and has no value
+	ErrMissingValue = cfgargs.ErrMissingValue
+	// ErrInvalidTag is reported for fields which cfgrant tag can't be parsed, e.g. quote is not closed
+	ErrInvalidTag = structs.ErrInvalidTag
+	// ErrUnsupportedType is reported by Check for fields which type can't be converted from string
+	ErrUnsupportedType = structs.ErrUnsupportedType
+	// ErrConflictingName is reported by Check when environment variable, command line argument or configuration file key is used by several fields
+	ErrConflictingName = structs.ErrConflictingName
+	// ErrInvalidExpansion is reported for values which references can't be expanded, e.g. references form a cycle
+	ErrInvalidExpansion = structs.ErrInvalidExpansion
+	// ErrHelp is returned by Process when -h or --help command line argument is passed, usage is printed in this case
+	ErrHelp = errors.New("configrant: help requested")
+)
+
+// Option configures Loader
+type Option func(*Loader)
+
+// WithSources replaces sources consulted by Loader. Sources are consulted in the given order,
+// the first one which finds a value for the field wins
+func WithSources(sources ...Source) Option {
+	return func(l *Loader) {
+		l.sources = sources
+	}
+}
+
+// WithArgs sets command line arguments (program name excluded) parsed by Loader, os.Args[1:] is used by default
+func WithArgs(args ...string) Option {
+	return func(l *Loader) {
+		l.args = append(make([]string, 0, len(args)), args...)
+	}
+}
+
+// WithLookupEnv sets function which looks up environment variables instead of the process environment.
+// Since variables can't be listed with it, elements of slices and maps of structs aren't discovered from environment
+func WithLookupEnv(lookup func(name string) (string, bool)) Option {
+	return withEnvironment(environment{lookup: lookup})
+}
+
+// WithEnvMap sets environment variables instead of the process environment, map is copied
+func WithEnvMap(env map[string]string) Option {
+	copied := make(map[string]string, len(env))
+	for name, value := range env {
+		copied[name] = value
+	}
+	return withEnvironment(mapEnvironment(copied))
+}
+
+// WithEnviron sets environment variables in os.Environ format (NAME=value) instead of the process environment,
+// e.g. environment of a child process. If variable is listed several times, the last value wins
+func WithEnviron(environ []string) Option {
+	env := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if nameValue := strings.SplitN(entry, "=", 2); len(nameValue) == 2 && nameValue[0] != "" {
+			env[nameValue[0]] = nameValue[1]
+		}
+	}
+	return withEnvironment(mapEnvironment(env))
+}
+
+// WithEnvFile adds variables from .env file to the environment set by previous options or to the process one.
+// Variables which are already present in the environment win. File is read on each Process call
+func WithEnvFile(path string) Option {
+	return func(l *Loader) {
+		base := l.env
+		l.env = func() (environment, error) {
+			env, err := base.get()
+			if err != nil {
+				return env, err
+			}
+			fileEnv, err := cfgfile.LoadEnv(path)
+			if err != nil {
+				return env, err
+			}
+			return env.fallback(mapEnvironment(fileEnv)), nil
+		}
+	}
+}
+
+func withEnvironment(env environment) Option {
+	return func(l *Loader) {
+		l.env = func() (environment, error) {
+			return env, nil
+		}
+	}
+}
+
+// WithUsageOutput sets writer for usage printed on -h or --help, os.Stderr is used by default
+func WithUsageOutput(w io.Writer) Option {
+	return func(l *Loader) {
+		l.usageOutput = w
+	}
+}
+
+// WithStrictArgs makes Process fail with ErrUnknownArg if command line argument doesn't belong to any field
+func WithStrictArgs() Option {
+	return func(l *Loader) {
+		l.strictArgs = true
+	}
+}
+
+// WithStrictTags makes Process run Check before consulting any source and fail if any problem is found
+func WithStrictTags() Option {
+	return func(l *Loader) {
+		l.strictTags = true
+	}
+}
+
+// WithValidator registers named validator for this Loader only, it takes precedence over validator registered by RegisterValidator
+func WithValidator(name string, validator Validator) Option {
+	return func(l *Loader) {
+		l.validators[name] = validator
+	}
+}
+
+// WithPreserveNonZero enables compatibility mode: fields which have non-zero value before
+// processing are never overwritten, regardless of sources order
+func WithPreserveNonZero() Option {
+	return func(l *Loader) {
+		l.preserveNonZero = true
+	}
+}
+
+// WithExpansion enables expansion of ${NAME} and ${NAME:-fallback} references in values of all fields,
+// not only in values of fields tagged with 'expand' option
+func WithExpansion() Option {
+	return func(l *Loader) {
+		l.expandAll = true
+	}
+}
+
+// WithDelimiters replaces hierarchy of delimiters which separate elements of slices, arrays and maps,
+// from the outermost level to the innermost one. Default hierarchy is ";", "|", "~", so [][]string
+// is written as a|b;c|d. Every nested level requires its own delimiter, delimiters must be non-empty and distinct,
+// otherwise Process and Check fail
+func WithDelimiters(delimiters ...string) Option {
+	return func(l *Loader) {
+		l.delimiters = delimiters
+	}
+}
+
+// Loader maintains configuration structures with values taken from its sources. Loader has no global state
+// and isn't modified by Process, so it can be used by several goroutines concurrently unless it has sources
+// which implement Preparer but not Binder
+type Loader struct {
+	sources     []Source
+	args        []string
+	env         environmentProvider
+	usageOutput io.Writer
+	strictArgs  bool
+	strictTags  bool
+	validators  map[string]Validator
+	naming      structs.Naming
+	converters  structs.Converters
+	delimiters  []string
+	expandAll   bool
+
+	preserveNonZero bool
+}
+
+// New creates Loader. By default command line arguments, environment variables, configuration file,
+// value field has before processing and default values are consulted in this order
+func New(opts ...Option) *Loader {
+	l := &Loader{
+		sources:     []Source{Args(), Env(), ConfigFile(), Literal(), Defaults()},
+		usageOutput: os.Stderr,
+		validators:  make(map[string]Validator),
+		converters:  make(structs.Converters),
+		naming: structs.Naming{
+			EnvCase: ScreamingSnakeCase,
+			ArgCase: KebabCase,
+		},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
 // Process apply values to structure fields correspondingly
-func Process(from interface{}) error {
-	cfgargs.Parse(os.Args)
-	cfg, err := structs.NewParser(from)
+func (l *Loader) Process(from interface{}) error {
+	_, err := l.process(from, nil)
+	return err
+}
+
+// process maintains configuration and returns sources bound to this call, record receives provenance of each field if set
+func (l *Loader) process(from interface{}, record structs.Recorder) ([]Source, error) {
+	cfg, err := l.parser(from)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Recorder = record
+	if l.strictTags {
+		if err := l.Check(from); err != nil {
+			return nil, err
+		}
+	}
+	fields, err := cfg.Fields()
+	if err != nil {
+		return nil, err
+	}
+	args, argsErr := cfgargs.Parse(l.arguments(), l.argSpecs(fields), l.strictArgs)
+	if args.HelpRequested() {
+		if err := l.Usage(from, l.usageOutput); err != nil {
+			return nil, err
+		}
+		return nil, ErrHelp
+	}
+	if argsErr != nil {
+		return nil, argsErr
+	}
+	env, err := l.env.get()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := l.bindSources(&processing{args: args, env: env})
+	if err != nil {
+		return nil, err
+	}
+	cfg.Expansion.LookupEnv = envSource{env: env}.environment().lookup
+	maintained := sources
+	if l.preserveNonZero {
+		maintained = append([]Source{Literal()}, sources...)
+	}
+	// fields which are maintained are still validated, so that all problems are reported at once
+	var failed Errors
+	if err := cfg.MaintainFields(maintained); err != nil && !errors.As(err, &failed) {
+		return sources, err
+	}
+	return sources, cfg.ValidateFields(l.registeredValidators(), failed)
+}
+
+// bindSources binds sources to processing state or to this call and prepares the others
+func (l *Loader) bindSources(p *processing) ([]Source, error) {
+	sources := make([]Source, 0, len(l.sources))
+	for _, src := range l.sources {
+		if b, ok := src.(binder); ok {
+			bound, err := b.bind(p)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, bound)
+			continue
+		}
+		if b, ok := src.(Binder); ok {
+			bound, err := b.Bind()
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, bound)
+			continue
+		}
+		if preparer, ok := src.(Preparer); ok {
+			if err := preparer.Prepare(); err != nil {
+				return nil, err
+			}
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+func (l *Loader) arguments() []string {
+	if l.args != nil {
+		return l.args
+	}
+	if len(os.Args) == 0 {
+		return nil
+	}
+	return os.Args[1:]
+}
+
+// Check reports every problem of configuration struct type without consulting any source: malformed tags,
+// unknown or duplicated options, empty names, unsupported field types, environment variables, command line arguments
+// and configuration file keys used by several fields. Passed configuration stays untouched, problems are returned as Errors
+func (l *Loader) Check(cfg interface{}) error {
+	typ := reflect.TypeOf(cfg)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return ErrNotPtrStruct
+	}
+	parser, err := l.parser(reflect.New(typ.Elem()).Interface())
 	if err != nil {
 		return err
 	}
-	return cfg.MaintainFields()
+	return parser.Check()
+}
+
+func (l *Loader) parser(from interface{}) (structs.Parser, error) {
+	cfg, err := structs.NewParser(from)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Naming = l.naming
+	cfg.Converters = l.registeredConverters()
+	cfg.Expansion.All = l.expandAll
+	if len(l.delimiters) > 0 {
+		if err := checkDelimiters(l.delimiters); err != nil {
+			return cfg, err
+		}
+		cfg.Delimiters = l.delimiters
+	}
+	return cfg, nil
+}
+
+// checkDelimiters rejects hierarchy set by WithDelimiters which can't split values unambiguously
+func checkDelimiters(delimiters []string) error {
+	for i, delimiter := range delimiters {
+		if delimiter == "" {
+			return fmt.Errorf("configrant: delimiter at level %d is empty", i)
+		}
+		for _, outer := range delimiters[:i] {
+			if outer == delimiter {
+				return fmt.Errorf("configrant: delimiter %q is used at several levels", delimiter)
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Loader) argSpecs(fields []structs.Field) []cfgargs.Spec {
+	specs := make([]cfgargs.Spec, 0, len(fields))
+	for _, field := range fields {
+		if field.Positional || (field.ArgName == "" && field.ShortArgName == "") {
+			continue
+		}
+		if field.IsCollection() {
+			boolNames := make([]string, 0, len(field.ElementBoolArgs))
+			for _, name := range field.ElementBoolArgs {
+				boolNames = append(boolNames, "*."+name)
+			}
+			specs = append(specs, cfgargs.Spec{Names: []string{field.ArgName}, Prefix: true, BoolNames: boolNames})
+			continue
+		}
+		specs = append(specs, cfgargs.Spec{
+			Names:  []string{field.ArgName, field.ShortArgName},
+			IsBool: field.Type.Kind() == reflect.Bool,
+		})
+	}
+	for _, src := range l.sources {
+		if fs, ok := src.(*fileSource); ok && fs.arg != "" {
+			specs = append(specs, cfgargs.Spec{Names: []string{fs.arg}})
+		}
+	}
+	return specs
+}
+
+// Check reports every problem of configuration struct type using default Loader, see Loader.Check
+func Check(cfg interface{}) error {
+	return New().Check(cfg)
+}
+
+// Process apply values to structure fields correspondingly using default Loader
+func Process(from interface{}) error {
+	return New().Process(from)
 }
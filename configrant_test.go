@@ -1,8 +1,21 @@
 package configrant
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -45,7 +58,7 @@ func TestProcessSuccess(t *testing.T) {
 	t.Setenv("DB_USERNAME", "dbmanager")
 
 	// set os.Args
-	os.Args = []string{"-async", "--timeout=7s"}
+	os.Args = []string{"configrant.test", "-async", "--timeout=7s"}
 
 	t.Log("Expect success parse of complex config struct")
 
@@ -121,9 +134,9 @@ func TestProcessSuccess(t *testing.T) {
 		t.Errorf(`Expect field 'Password' to be equal "", got %s`, cfg.Password)
 	}
 
-	// Field is tagged, but it has non zero value, so isn't overwritten
-	if cfg.UserName != "admin" {
-		t.Errorf(`Expect field 'Username' to be equal "admin", got %s`, cfg.UserName)
+	// Field has non zero value, but env is set and has priority over struct value
+	if cfg.UserName != "dbmanager" {
+		t.Errorf(`Expect field 'Username' to be equal "dbmanager", got %s`, cfg.UserName)
 	}
 
 	// Expect to be default, because env is specified, but not set
@@ -159,3 +172,2039 @@ func TestProcessNotPtrStructPassed(t *testing.T) {
 		t.Fatal("Not pointer to struct has been passed and was no error")
 	}
 }
+
+func TestProcessFieldErrors(t *testing.T) {
+	type InvalidSubstruct struct {
+		Ratio float64 `cfgrant:"default:high"`
+	}
+	type InvalidConfig struct {
+		Retries int           `cfgrant:"env:RETRIES_ENV,default:3"`
+		Timeout time.Duration `cfgrant:"default:5 seconds"`
+		Bytes   []byte        `cfgrant:"default:1;2;300"`
+		Name    string        `cfgrant:"default:configrant"`
+		Sub     InvalidSubstruct
+	}
+
+	t.Setenv("RETRIES_ENV", "three")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect every invalid field to be reported")
+
+	cfg := &InvalidConfig{}
+	err := Process(cfg)
+	if err == nil {
+		t.Fatal("Expect error for invalid values, got nil")
+	}
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect error of type Errors, got %T", err)
+	}
+	if len(errs) != 4 {
+		t.Fatalf("Expect 4 field errors, got %d: %v", len(errs), err)
+	}
+
+	expected := []FieldError{
+		{Path: "Retries", Source: "env", Value: "three"},
+		{Path: "Timeout", Source: "default", Value: "5 seconds"},
+		{Path: "Bytes", Source: "default", Value: "1;2;300"},
+		{Path: "Sub.Ratio", Source: "default", Value: "high"},
+	}
+	for i, fieldErr := range errs {
+		if fieldErr.Path != expected[i].Path || fieldErr.Source != expected[i].Source || fieldErr.Value != expected[i].Value {
+			t.Errorf("Expect error %d to be for field %s from %s with value %q, got %s from %s with value %q",
+				i, expected[i].Path, expected[i].Source, expected[i].Value, fieldErr.Path, fieldErr.Source, fieldErr.Value)
+		}
+	}
+
+	// valid fields are still maintained
+	if cfg.Name != "configrant" {
+		t.Errorf(`Expect field 'Name' to be equal "configrant", got %s`, cfg.Name)
+	}
+
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Error("Expect errors.Is to find strconv.ErrSyntax")
+	}
+
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Error("Expect errors.As to find *strconv.NumError")
+	}
+
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "Retries" {
+		t.Error("Expect errors.As to find *FieldError for field 'Retries'")
+	}
+}
+
+type FileSubstruct struct {
+	Subname string `cfgrant:"default:SubConfig"`
+	Percent float32
+}
+
+type FileConfig struct {
+	Url      string         `cfgrant:"env:URL_ENV,default:http://localhost:3000"`
+	Retries  int            `cfgrant:"env:RETRIES_ENV,default:3"`
+	Bytes    []byte         `cfgrant:"default:1;2;3"`
+	Sequence map[string]int `cfgrant:"key:seq"`
+	Timeout  time.Duration  `cfgrant:"default:5s,arg:--timeout"`
+	IsAsync  bool           `cfgrant:"default:false"`
+	Sub      FileSubstruct  `cfgrant:"key:substruct"`
+}
+
+func TestProcessConfigFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+url: http://example.com
+retries: 5
+bytes: [4, 5, 6]
+seq:
+  first: 1
+  second: 2
+timeout: 10s
+isAsync: true
+substruct:
+  percent: 1.5
+`,
+		"config.json": `{
+	"url": "http://example.com",
+	"retries": 5,
+	"bytes": [4, 5, 6],
+	"seq": {"first": 1, "second": 2},
+	"timeout": "10s",
+	"isAsync": true,
+	"substruct": {"percent": 1.5}
+}`,
+		"config.toml": `
+url = "http://example.com"
+retries = 5
+bytes = [4, 5, 6]
+timeout = "10s"
+isAsync = true
+
+[seq]
+first = 1
+second = 2
+
+[substruct]
+percent = 1.5
+`,
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("URL_ENV", "http://env.example.com")
+			os.Args = []string{"configrant.test", "--timeout=7s", "--config", path}
+
+			cfg := &FileConfig{}
+			if err := Process(cfg); err != nil {
+				t.Fatalf("Error occured during parsing %s", err.Error())
+			}
+
+			// env has priority over file
+			if cfg.Url != "http://env.example.com" {
+				t.Errorf(`Expect field 'Url' to be equal "http://env.example.com", got %s`, cfg.Url)
+			}
+
+			// file has priority over default
+			if cfg.Retries != 5 {
+				t.Errorf("Expect field 'Retries' to be equal 5, got %d", cfg.Retries)
+			}
+			if !reflect.DeepEqual(cfg.Bytes, []byte{4, 5, 6}) {
+				t.Errorf("Expect field 'Bytes' to be equal [4 5 6], got %v", cfg.Bytes)
+			}
+			if !reflect.DeepEqual(cfg.Sequence, map[string]int{"first": 1, "second": 2}) {
+				t.Errorf("Expect field 'Sequence' to be equal map[first:1 second:2], got %v", cfg.Sequence)
+			}
+			if !cfg.IsAsync {
+				t.Error("Expect field 'IsAsync' to be equal true, got false")
+			}
+
+			// arg has priority over file
+			if cfg.Timeout != 7*time.Second {
+				t.Errorf("Expect field 'Timeout' to be equal 7s, got %s", cfg.Timeout)
+			}
+
+			// nested keys are applied to substructures, missing keys fall back to default
+			if cfg.Sub.Percent != 1.5 {
+				t.Errorf("Expect inner struct field 'Percent' to be equal 1.5, got %.2f", cfg.Sub.Percent)
+			}
+			if cfg.Sub.Subname != "SubConfig" {
+				t.Errorf(`Expect inner struct field 'Subname' to be equal "SubConfig", got %s`, cfg.Sub.Subname)
+			}
+		})
+	}
+
+	t.Run("json numbers", func(t *testing.T) {
+		path := filepath.Join(dir, "numbers.json")
+		if err := os.WriteFile(path, []byte(`{"id": 9007199254740993, "ratio": 0.1, "ids": [9007199254740993]}`), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		cfg := &struct {
+			ID    int64
+			Ratio float32
+			IDs   []int64
+		}{}
+		if err := New(WithSources(File(path)), WithArgs()).Process(cfg); err != nil {
+			t.Fatalf("Error occured during parsing %s", err.Error())
+		}
+		if cfg.ID != 9007199254740993 || cfg.Ratio != 0.1 || !reflect.DeepEqual(cfg.IDs, []int64{9007199254740993}) {
+			t.Errorf("Expect numbers to be loaded without precision loss, got %+v", *cfg)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(ConfigFileEnv, filepath.Join(dir, "config.yaml"))
+		os.Args = []string{"configrant.test"}
+
+		cfg := &FileConfig{}
+		if err := Process(cfg); err != nil {
+			t.Fatalf("Error occured during parsing %s", err.Error())
+		}
+		if cfg.Url != "http://example.com" {
+			t.Errorf(`Expect field 'Url' to be equal "http://example.com", got %s`, cfg.Url)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		os.Args = []string{"configrant.test", "--config=" + filepath.Join(dir, "config.ini")}
+		if err := Process(&FileConfig{}); err == nil {
+			t.Error("Expect error for unsupported config file format, got nil")
+		}
+	})
+}
+
+type mapSource map[string]string
+
+func (s mapSource) Name() string {
+	return "map"
+}
+
+func (s mapSource) Lookup(field *FieldInfo) (string, bool, error) {
+	value, ok := s[field.Path]
+	return value, ok, nil
+}
+
+type failingSource struct{}
+
+func (failingSource) Name() string {
+	return "failing"
+}
+
+func (failingSource) Lookup(field *FieldInfo) (string, bool, error) {
+	return "", false, errors.New("source is unavailable")
+}
+
+// bindingSource loads its value on Bind, so source passed to Loader stays untouched
+type bindingSource struct {
+	value string
+	binds *int32
+}
+
+func (bindingSource) Name() string {
+	return "binding"
+}
+
+func (s bindingSource) Lookup(field *FieldInfo) (string, bool, error) {
+	return s.value, s.value != "" && field.Path == "Name", nil
+}
+
+func (s bindingSource) Bind() (Source, error) {
+	return bindingSource{value: fmt.Sprintf("bound-%d", atomic.AddInt32(s.binds, 1))}, nil
+}
+
+func TestLoaderSources(t *testing.T) {
+	type SourcesConfig struct {
+		Name    string `cfgrant:"env:NAME_ENV,default:default"`
+		Retries int    `cfgrant:"default:3"`
+		Count   int
+		Sub     ConfigSubstruct
+	}
+
+	t.Setenv("NAME_ENV", "env")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect custom source to be consulted in the configured order")
+
+	loader := New(WithSources(mapSource{"Name": "map", "Sub.Subname": "mapped"}, Env(), Defaults()))
+	cfg := &SourcesConfig{}
+	if err := loader.Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+
+	// map source is consulted first
+	if cfg.Name != "map" {
+		t.Errorf(`Expect field 'Name' to be equal "map", got %s`, cfg.Name)
+	}
+	if cfg.Sub.Subname != "mapped" {
+		t.Errorf(`Expect inner struct field 'Subname' to be equal "mapped", got %s`, cfg.Sub.Subname)
+	}
+
+	// fields unknown to map source fall back to next sources
+	if cfg.Retries != 3 {
+		t.Errorf("Expect field 'Retries' to be equal 3, got %d", cfg.Retries)
+	}
+
+	// no source supplies value, so field stays zero-valued without error
+	if cfg.Count != 0 {
+		t.Errorf("Expect field 'Count' to be equal 0, got %d", cfg.Count)
+	}
+
+	t.Log("Expect source failure to be reported")
+
+	err := New(WithSources(failingSource{})).Process(&SourcesConfig{})
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Source != "failing" {
+		t.Errorf("Expect field error from source 'failing', got %v", err)
+	}
+}
+
+func TestProcessRequiredFields(t *testing.T) {
+	type RequiredSubstruct struct {
+		Token string `cfgrant:"env:TOKEN_ENV,required"`
+	}
+	type RequiredConfig struct {
+		Password string        `cfgrant:"env:DB_PASSWORD,required"`
+		User     string        `cfgrant:"env:DB_USER,required"`
+		Port     int           `cfgrant:"env:DB_PORT,default:5432,required"`
+		Timeout  time.Duration `cfgrant:"required"`
+		Sub      RequiredSubstruct
+	}
+
+	t.Setenv("DB_USER", "admin")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect every required field without value to be reported")
+
+	cfg := &RequiredConfig{Timeout: time.Second}
+	err := Process(cfg)
+	if !errors.Is(err, ErrRequired) {
+		t.Fatalf("Expect ErrRequired, got %v", err)
+	}
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect error of type Errors, got %T", err)
+	}
+	paths := make([]string, 0, len(errs))
+	for _, fieldErr := range errs {
+		paths = append(paths, fieldErr.Path)
+	}
+	// env and default supply values for 'User' and 'Port', 'Timeout' is initialized
+	if !reflect.DeepEqual(paths, []string{"Password", "Sub.Token"}) {
+		t.Errorf("Expect required errors for [Password Sub.Token], got %v", paths)
+	}
+}
+
+type UsageConfig struct {
+	Url     string        `cfgrant:"arg:--url,env:URL_ENV,default:http://localhost:3000,desc:API endpoint"`
+	Timeout time.Duration `cfgrant:"arg:--timeout,default:5s"`
+	Token   string        `cfgrant:"env:TOKEN_ENV,required,desc:access token"`
+	Sub     ConfigSubstruct
+}
+
+func TestUsage(t *testing.T) {
+	t.Log("Expect plain-text usage to describe every field")
+
+	var text bytes.Buffer
+	if err := Usage(&UsageConfig{}, &text); err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	expectedText := `FIELD        ARG        ENV          TYPE           DEFAULT                DESCRIPTION
+Url          --url      URL_ENV      string         http://localhost:3000  API endpoint
+Timeout      --timeout  -            time.Duration  5s                     -
+Token        -          TOKEN_ENV    string         -                      access token (required)
+Sub.Subname  -          SUBNAME_ENV  string         SubConfig              -
+Sub.Percent  -          -            float32        3.32                   -
+`
+	if text.String() != expectedText {
+		t.Errorf("Expect usage to be\n%s\ngot\n%s", expectedText, text.String())
+	}
+
+	t.Log("Expect Markdown usage to describe every field")
+
+	var md bytes.Buffer
+	if err := UsageMarkdown(&UsageConfig{}, &md); err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	expectedMarkdown := "| FIELD | ARG | ENV | TYPE | DEFAULT | DESCRIPTION |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n" +
+		"| `Url` | `--url` | `URL_ENV` | `string` | `http://localhost:3000` | API endpoint |\n" +
+		"| `Timeout` | `--timeout` |  | `time.Duration` | `5s` |  |\n" +
+		"| `Token` |  | `TOKEN_ENV` | `string` |  | access token (required) |\n" +
+		"| `Sub.Subname` |  | `SUBNAME_ENV` | `string` | `SubConfig` |  |\n" +
+		"| `Sub.Percent` |  |  | `float32` | `3.32` |  |\n"
+	if md.String() != expectedMarkdown {
+		t.Errorf("Expect usage to be\n%s\ngot\n%s", expectedMarkdown, md.String())
+	}
+
+	t.Log("Expect usage to be printed on --help")
+
+	os.Args = []string{"configrant.test", "--help"}
+	var help bytes.Buffer
+	cfg := &UsageConfig{}
+	if err := New(WithUsageOutput(&help)).Process(cfg); err != ErrHelp {
+		t.Fatalf("Expect ErrHelp, got %v", err)
+	}
+	if help.String() != expectedText {
+		t.Errorf("Expect usage to be\n%s\ngot\n%s", expectedText, help.String())
+	}
+	if cfg.Url != "" {
+		t.Errorf(`Expect field 'Url' to stay "", got %s`, cfg.Url)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	type WatchConfig struct {
+		LogLevel string        `cfgrant:"default:info"`
+		Timeout  time.Duration `cfgrant:"default:5s"`
+		OwnerPtr *string       `cfgrant:"default:James"`
+		Retries  int
+		Password string `cfgrant:"secret"`
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("loglevel: debug\npassword: one\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	os.Args = []string{"configrant.test", "--config=" + path}
+
+	t.Log("Expect watcher to reload configuration on file change")
+
+	cfg := &WatchConfig{Retries: 3}
+	w, err := Watch(cfg, WatchInterval(10*time.Millisecond), WatchSignals())
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	defer w.Close()
+
+	if cfg.LogLevel != "debug" {
+		t.Errorf(`Expect field 'LogLevel' to be equal "debug", got %s`, cfg.LogLevel)
+	}
+
+	reloaded := make(chan []Change, 1)
+	w.OnChange(func(cfg interface{}, changes []Change) {
+		reloaded <- changes
+	})
+
+	if err := os.WriteFile(path, []byte("loglevel: warn\ntimeout: 10s\npassword: two\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	var changes []Change
+	select {
+	case changes = <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expect configuration to be reloaded after file change")
+	}
+
+	expected := []Change{
+		{Path: "LogLevel", Old: "debug", New: "warn"},
+		{Path: "Timeout", Old: 5 * time.Second, New: 10 * time.Second},
+		{Path: "Password", Old: RedactedValue, New: RedactedValue},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expect changes to be %v, got %v", expected, changes)
+	}
+
+	// the old snapshot stays untouched
+	if cfg.LogLevel != "debug" || cfg.Timeout != 5*time.Second {
+		t.Errorf("Expect old snapshot to stay unchanged, got %s and %s", cfg.LogLevel, cfg.Timeout)
+	}
+
+	current := w.Config().(*WatchConfig)
+	if current.LogLevel != "warn" || current.Timeout != 10*time.Second {
+		t.Errorf("Expect current snapshot to have new values, got %s and %s", current.LogLevel, current.Timeout)
+	}
+	// values from the initial struct are kept, pointers are not shared between snapshots
+	if current.Retries != 3 {
+		t.Errorf("Expect field 'Retries' to be equal 3, got %d", current.Retries)
+	}
+	if current.OwnerPtr == cfg.OwnerPtr {
+		t.Error("Expect snapshots to have different pointers for field 'OwnerPtr'")
+	}
+
+	t.Log("Expect failed reload to keep the current snapshot")
+
+	if err := os.WriteFile(path, []byte("timeout: soon\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := w.Reload(); err == nil {
+		t.Error("Expect error on reload with invalid value, got nil")
+	}
+	if w.Config() != current {
+		t.Error("Expect current snapshot to be kept after failed reload")
+	}
+
+	t.Log("Expect non-positive interval to be rejected")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := Watch(&WatchConfig{}, WatchInterval(interval)); err == nil || !strings.Contains(err.Error(), "interval") {
+			t.Errorf("Expect interval error for %s, got %v", interval, err)
+		}
+	}
+
+	t.Log("Expect fields of collection elements to be compared one by one")
+
+	type WatchUpstream struct {
+		Host string
+		Pass string `cfgrant:"secret"`
+	}
+	type CollectionWatchConfig struct {
+		Ups []WatchUpstream
+	}
+	path = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("ups:\n  - host: a\n    pass: s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	w, err = New(WithSources(File(path)), WithArgs()).Watch(&CollectionWatchConfig{}, WatchSignals())
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	defer w.Close()
+	collectionReloaded := make(chan []Change, 1)
+	w.OnChange(func(cfg interface{}, changes []Change) {
+		collectionReloaded <- changes
+	})
+	if err := os.WriteFile(path, []byte("ups:\n  - host: b\n    pass: n3wsecret\n  - host: c\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	expected = []Change{
+		{Path: "Ups[0].Host", Old: "a", New: "b"},
+		{Path: "Ups[0].Pass", Old: RedactedValue, New: RedactedValue},
+		{Path: "Ups[1].Host", Old: nil, New: "c"},
+		{Path: "Ups[1].Pass", Old: nil, New: ""},
+	}
+	if changes := <-collectionReloaded; !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expect changes to be %v, got %v", expected, changes)
+	}
+
+	t.Log("Expect values which reference secret fields to be redacted in changes")
+
+	type ExpandedWatchConfig struct {
+		Password string `cfgrant:"secret"`
+		DSN      string `cfgrant:"default:postgres://app:${Password}@db,expand"`
+	}
+	if err := os.WriteFile(path, []byte("password: one\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	w, err = New(WithSources(File(path), Defaults()), WithArgs()).Watch(&ExpandedWatchConfig{}, WatchSignals())
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	defer w.Close()
+	w.OnChange(func(cfg interface{}, changes []Change) {
+		collectionReloaded <- changes
+	})
+	if err := os.WriteFile(path, []byte("password: two\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	expected = []Change{
+		{Path: "Password", Old: RedactedValue, New: RedactedValue},
+		{Path: "DSN", Old: RedactedValue, New: RedactedValue},
+	}
+	if changes := <-collectionReloaded; !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expect changes to be %v, got %v", expected, changes)
+	}
+}
+
+func TestProcessArgs(t *testing.T) {
+	type ArgsConfig struct {
+		Name    string        `cfgrant:"arg:--name,short:-n"`
+		Timeout time.Duration `cfgrant:"arg:--timeout"`
+		Retries int           `cfgrant:"arg:--retries,short:-r,default:3"`
+		Verbose bool          `cfgrant:"arg:--verbose,short:-v"`
+		All     bool          `cfgrant:"short:-a"`
+		Cache   bool          `cfgrant:"arg:--cache,default:true"`
+		Legacy  bool          `cfgrant:"arg:-legacy"`
+		Files   []string      `cfgrant:"positional"`
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected ArgsConfig
+	}{
+		{
+			name:     "separate values",
+			args:     []string{"app", "--name", "server", "--timeout", "7s", "-r", "5"},
+			expected: ArgsConfig{Name: "server", Timeout: 7 * time.Second, Retries: 5, Cache: true},
+		},
+		{
+			name:     "attached values",
+			args:     []string{"app", "--name=server", "-r5", "-legacy"},
+			expected: ArgsConfig{Name: "server", Retries: 5, Cache: true, Legacy: true},
+		},
+		{
+			name:     "combined short flags",
+			args:     []string{"app", "-van", "server"},
+			expected: ArgsConfig{Name: "server", Retries: 3, Verbose: true, All: true, Cache: true},
+		},
+		{
+			name:     "negation and positional",
+			args:     []string{"app", "a.txt", "--no-cache", "b.txt", "--", "--name", "-v"},
+			expected: ArgsConfig{Retries: 3, Files: []string{"a.txt", "b.txt", "--name", "-v"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			os.Args = c.args
+			cfg := &ArgsConfig{}
+			if err := Process(cfg); err != nil {
+				t.Fatalf("Error occured during parsing %s", err.Error())
+			}
+			if !reflect.DeepEqual(*cfg, c.expected) {
+				t.Errorf("Expect config to be %+v, got %+v", c.expected, *cfg)
+			}
+		})
+	}
+
+	t.Log("Expect unknown arguments to fail in strict mode only")
+
+	os.Args = []string{"app", "--name=server", "--unknown", "-x"}
+	if err := Process(&ArgsConfig{}); err != nil {
+		t.Errorf("Expect unknown arguments to be ignored, got %v", err)
+	}
+	err := New(WithStrictArgs()).Process(&ArgsConfig{})
+	if !errors.Is(err, ErrUnknownArg) {
+		t.Errorf("Expect ErrUnknownArg in strict mode, got %v", err)
+	}
+
+	t.Log("Expect non-boolean argument without value to be reported")
+
+	for _, args := range [][]string{{"app", "--name"}, {"app", "-r"}, {"app", "-vn"}} {
+		os.Args = args
+		err := Process(&ArgsConfig{})
+		if !errors.Is(err, ErrMissingValue) || !strings.HasPrefix(err.Error(), "configrant: ") {
+			t.Errorf("Expect ErrMissingValue for %v, got %v", args[1:], err)
+		}
+	}
+	os.Args = []string{"app", "--unknown"}
+	if err := New(WithStrictArgs()).Process(&ArgsConfig{}); err == nil || !strings.HasPrefix(err.Error(), "configrant: ") {
+		t.Errorf("Expect ErrUnknownArg with configrant prefix, got %v", err)
+	}
+
+	t.Log("Expect short aliases without leading dash to be single-dash flags")
+
+	type ShortConfig struct {
+		Verbose bool   `cfgrant:"arg:verbose,short:v"`
+		Level   int    `cfgrant:"arg:level,short:l"`
+		Host    string `cfgrant:"arg:host,short:h"`
+	}
+	shortCases := map[string]ShortConfig{
+		"-v -l 5 -h example.com": {Verbose: true, Level: 5, Host: "example.com"},
+		"-vl5":                   {Verbose: true, Level: 5},
+		"-v=false -l=2 -hlocal":  {Level: 2, Host: "local"},
+	}
+	for args, expected := range shortCases {
+		os.Args = append([]string{"app"}, strings.Fields(args)...)
+		cfg := &ShortConfig{}
+		if err := New(WithStrictArgs()).Process(cfg); err != nil {
+			t.Fatalf("Error occured during parsing %s: %s", args, err.Error())
+		}
+		if *cfg != expected {
+			t.Errorf("Expect config to be %+v for %s, got %+v", expected, args, *cfg)
+		}
+	}
+
+	t.Log("Expect help to be requested by names which are not claimed by fields only")
+
+	os.Args = []string{"app", "-h", "example.com", "--help"}
+	if err := New(WithUsageOutput(&strings.Builder{})).Process(&ShortConfig{}); err != ErrHelp {
+		t.Errorf("Expect ErrHelp for --help, got %v", err)
+	}
+}
+
+type ValidatedSubstruct struct {
+	Host string `cfgrant:"default:localhost"`
+	Port int    `cfgrant:"default:8080"`
+}
+
+func (s *ValidatedSubstruct) Validate() error {
+	if s.Host == "localhost" && s.Port == 80 {
+		return errors.New("privileged port on localhost")
+	}
+	return nil
+}
+
+type ValidatedConfig struct {
+	Retries  int               `cfgrant:"env:RETRIES_ENV,default:3,min:1,max:5"`
+	Ratio    float64           `cfgrant:"default:0.5,min:0,max:1"`
+	Name     string            `cfgrant:"default:configrant,min:3,max:8"`
+	Level    string            `cfgrant:"env:LEVEL_ENV,default:info,oneof:debug|info|warn"`
+	Levels   []string          `cfgrant:"default:info;debug,oneof:debug|info|warn"`
+	Version  string            `cfgrant:"default:1.2.3,pattern:^[0-9]+\\.[0-9]+\\.[0-9]+$"`
+	Timeout  time.Duration     `cfgrant:"default:5s,min:1s,max:1m"`
+	Tags     map[string]string `cfgrant:"default:a:1;b:2,max:1"`
+	Even     int               `cfgrant:"default:4,validate:even"`
+	Upstream ValidatedSubstruct
+}
+
+func TestProcessValidation(t *testing.T) {
+	RegisterValidator("even", func(value interface{}) error {
+		if value.(int)%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+
+	t.Setenv("RETRIES_ENV", "10")
+	t.Setenv("LEVEL_ENV", "trace")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect every violated rule to be reported")
+
+	err := Process(&ValidatedConfig{Upstream: ValidatedSubstruct{Port: 80}, Version: "v1", Even: 3})
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Expect ErrInvalid, got %v", err)
+	}
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect error of type Errors, got %T", err)
+	}
+	violations := make([]string, 0, len(errs))
+	for _, fieldErr := range errs {
+		var validationErr *ValidationError
+		if !errors.As(fieldErr, &validationErr) {
+			t.Fatalf("Expect ValidationError, got %v", fieldErr)
+		}
+		violations = append(violations, fieldErr.Path+":"+validationErr.Rule)
+	}
+	expected := []string{"Retries:max", "Name:max", "Level:oneof", "Version:pattern", "Tags:max", "Even:even", "Upstream:Validate"}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expect violations %v, got %v", expected, violations)
+	}
+
+	t.Log("Expect loader validator to take precedence over registered one")
+
+	t.Setenv("RETRIES_ENV", "2")
+	t.Setenv("LEVEL_ENV", "warn")
+	loader := New(WithValidator("even", func(value interface{}) error { return nil }))
+	if err := loader.Process(&ValidatedConfig{Name: "app", Tags: map[string]string{"a": "1"}, Even: 3}); err != nil {
+		t.Errorf("Expect no validation errors, got %v", err)
+	}
+
+	t.Log("Expect fields which are maintained to be validated along with fields which failed")
+
+	t.Setenv("RETRIES_ENV", "many")
+	t.Setenv("LEVEL_ENV", "trace")
+	err = loader.Process(&ValidatedConfig{Name: "app", Tags: map[string]string{"a": "1"}, Upstream: ValidatedSubstruct{Port: 80}})
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect error of type Errors, got %v", err)
+	}
+	paths := make([]string, 0, len(errs))
+	for _, fieldErr := range errs {
+		paths = append(paths, fieldErr.Path)
+	}
+	if expected := []string{"Retries", "Level", "Upstream"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expect errors for %v, got %v", expected, err)
+	}
+	if errs[0].Source != "env" || errors.Is(errs[0], ErrInvalid) {
+		t.Errorf("Expect parse error for field 'Retries' to be reported without its rules, got %v", errs[0])
+	}
+
+	t.Log("Expect Validate of struct to be skipped when its field failed")
+
+	t.Setenv("RETRIES_ENV", "2")
+	t.Setenv("LEVEL_ENV", "warn")
+	t.Setenv("APP_UPSTREAM_PORT", "eighty")
+	loader = New(WithValidator("even", func(value interface{}) error { return nil }), WithAutoEnv("APP"))
+	err = loader.Process(&ValidatedConfig{Name: "app", Tags: map[string]string{"a": "1"}, Upstream: ValidatedSubstruct{Port: 80}})
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != "Upstream.Port" || errors.Is(err, ErrInvalid) {
+		t.Errorf("Expect only error for field 'Upstream.Port', got %v", err)
+	}
+}
+
+func TestProcessPrecedence(t *testing.T) {
+	type PrecedenceConfig struct {
+		IsAsync bool   `cfgrant:"env:ASYNC_ENV,default:true"`
+		Retries int    `cfgrant:"env:RETRIES_ENV,default:3"`
+		Name    string `cfgrant:"default:default"`
+		Owner   string `cfgrant:"env:OWNER_ENV,default:James"`
+	}
+
+	t.Setenv("ASYNC_ENV", "false")
+	t.Setenv("RETRIES_ENV", "0")
+	t.Setenv("OWNER_ENV", "Ronald")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect explicit source values to win over struct values and struct values to win over defaults")
+
+	cfg := &PrecedenceConfig{IsAsync: true, Retries: 5, Name: "literal"}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expected := PrecedenceConfig{IsAsync: false, Retries: 0, Name: "literal", Owner: "Ronald"}
+	if *cfg != expected {
+		t.Errorf("Expect config to be %+v, got %+v", expected, *cfg)
+	}
+
+	t.Log("Expect non-zero struct values to be preserved in compatibility mode")
+
+	cfg = &PrecedenceConfig{IsAsync: true, Retries: 5, Name: "literal"}
+	if err := New(WithPreserveNonZero()).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expected = PrecedenceConfig{IsAsync: true, Retries: 5, Name: "literal", Owner: "Ronald"}
+	if *cfg != expected {
+		t.Errorf("Expect config to be %+v, got %+v", expected, *cfg)
+	}
+
+	t.Log("Expect custom order to put struct values above env")
+
+	cfg = &PrecedenceConfig{IsAsync: true, Retries: 5}
+	if err := New(WithSources(Literal(), Env(), Defaults())).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expected = PrecedenceConfig{IsAsync: true, Retries: 5, Name: "default", Owner: "Ronald"}
+	if *cfg != expected {
+		t.Errorf("Expect config to be %+v, got %+v", expected, *cfg)
+	}
+}
+
+func TestProcessAutoNames(t *testing.T) {
+	type ServerConfig struct {
+		HTTPPort int    `cfgrant:"default:8080"`
+		Host     string `cfgrant:"env:SERVER_HOST"`
+	}
+	type NamedConfig struct {
+		LogLevel string `cfgrant:"default:info"`
+		Server   ServerConfig
+		Database ConfigSubstruct `cfgrant:"prefix:db"`
+	}
+
+	t.Setenv("APP_LOG_LEVEL", "debug")
+	t.Setenv("APP_SERVER_HTTP_PORT", "9090")
+	t.Setenv("SERVER_HOST", "example.com")
+	t.Setenv("APP_DB_PERCENT", "1.5")
+	os.Args = []string{"configrant.test", "--db-subname=args"}
+
+	t.Log("Expect env names to be derived from field path with prefix")
+
+	cfg := &NamedConfig{}
+	if err := New(WithAutoEnv("APP"), WithAutoArgs(), WithArgCase(KebabCase)).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf(`Expect field 'LogLevel' to be equal "debug", got %s`, cfg.LogLevel)
+	}
+	if cfg.Server.HTTPPort != 9090 {
+		t.Errorf("Expect inner struct field 'HTTPPort' to be equal 9090, got %d", cfg.Server.HTTPPort)
+	}
+	// explicit env option is honoured
+	if cfg.Server.Host != "example.com" {
+		t.Errorf(`Expect inner struct field 'Host' to be equal "example.com", got %s`, cfg.Server.Host)
+	}
+	if cfg.Database.Percent != 1.5 {
+		t.Errorf("Expect inner struct field 'Percent' to be equal 1.5, got %.2f", cfg.Database.Percent)
+	}
+	// arg name is derived with struct prefix
+	if cfg.Database.Subname != "args" {
+		t.Errorf(`Expect inner struct field 'Subname' to be equal "args", got %s`, cfg.Database.Subname)
+	}
+
+	t.Log("Expect derived names in usage")
+
+	var usage bytes.Buffer
+	if err := New(WithAutoEnv(""), WithEnvCase(SnakeCase), WithAutoArgs()).Usage(&NamedConfig{}, &usage); err != nil {
+		t.Fatalf("Unexpected error occurred: %v", err)
+	}
+	expectedUsage := `FIELD             ARG                 ENV               TYPE     DEFAULT    DESCRIPTION
+LogLevel          --log-level         log_level         string   info       -
+Server.HTTPPort   --server-http-port  server_http_port  int      8080       -
+Server.Host       --server-host       SERVER_HOST       string   -          -
+Database.Subname  --db-subname        SUBNAME_ENV       string   SubConfig  -
+Database.Percent  --db-percent        db_percent        float32  3.32       -
+`
+	if usage.String() != expectedUsage {
+		t.Errorf("Expect usage to be\n%s\ngot\n%s", expectedUsage, usage.String())
+	}
+}
+
+type LogLevel int
+
+const (
+	DebugLevel LogLevel = iota
+	InfoLevel
+	WarnLevel
+)
+
+func (l *LogLevel) Decode(value string) error {
+	switch strings.ToLower(value) {
+	case "debug":
+		*l = DebugLevel
+	case "info":
+		*l = InfoLevel
+	case "warn":
+		*l = WarnLevel
+	default:
+		return fmt.Errorf("unknown log level %s", value)
+	}
+	return nil
+}
+
+func TestProcessUnmarshalers(t *testing.T) {
+	type UnmarshalersConfig struct {
+		Level     LogLevel   `cfgrant:"env:LEVEL_ENV,default:info"`
+		Levels    []LogLevel `cfgrant:"default:debug;warn"`
+		IP        net.IP     `cfgrant:"default:192.168.0.1"`
+		Resolvers []net.IP   `cfgrant:"default:1.1.1.1;8.8.8.8"`
+		Big       *big.Int   `cfgrant:"default:123456789012345678901234567890"`
+		Endpoint  url.URL    `cfgrant:"default:https://example.com/api"`
+		Started   time.Time  `cfgrant:"default:2022-01-02T15:04:05Z"`
+	}
+
+	t.Setenv("LEVEL_ENV", "warn")
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect fields to be decoded with Decoder, TextUnmarshaler and BinaryUnmarshaler")
+
+	cfg := &UnmarshalersConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Level != WarnLevel {
+		t.Errorf("Expect field 'Level' to be equal %d, got %d", WarnLevel, cfg.Level)
+	}
+	if !reflect.DeepEqual(cfg.Levels, []LogLevel{DebugLevel, WarnLevel}) {
+		t.Errorf("Expect field 'Levels' to be equal [0 2], got %v", cfg.Levels)
+	}
+	if !cfg.IP.Equal(net.ParseIP("192.168.0.1")) {
+		t.Errorf("Expect field 'IP' to be equal 192.168.0.1, got %s", cfg.IP)
+	}
+	if len(cfg.Resolvers) != 2 || !cfg.Resolvers[1].Equal(net.ParseIP("8.8.8.8")) {
+		t.Errorf("Expect field 'Resolvers' to be equal [1.1.1.1 8.8.8.8], got %v", cfg.Resolvers)
+	}
+	if cfg.Big == nil || cfg.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("Expect field 'Big' to be equal 123456789012345678901234567890, got %v", cfg.Big)
+	}
+	if cfg.Endpoint.Host != "example.com" || cfg.Endpoint.Path != "/api" {
+		t.Errorf("Expect field 'Endpoint' to be equal https://example.com/api, got %s", cfg.Endpoint.String())
+	}
+	if !cfg.Started.Equal(time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expect field 'Started' to be equal 2022-01-02T15:04:05Z, got %s", cfg.Started)
+	}
+
+	t.Log("Expect decoding error to be reported")
+
+	t.Setenv("LEVEL_ENV", "trace")
+	var fieldErr *FieldError
+	if err := Process(&UnmarshalersConfig{}); !errors.As(err, &fieldErr) || fieldErr.Path != "Level" {
+		t.Errorf("Expect error for field 'Level', got %v", err)
+	}
+}
+
+type Point struct {
+	X, Y int
+}
+
+func parsePoint(value string) (Point, error) {
+	var p Point
+	_, err := fmt.Sscanf(value, "%dx%d", &p.X, &p.Y)
+	return p, err
+}
+
+type Celsius float64
+
+func TestProcessConverters(t *testing.T) {
+	type ConvertersConfig struct {
+		Origin    Point            `cfgrant:"default:1x2"`
+		Path      []Point          `cfgrant:"default:1x1;2x2"`
+		Named     map[Point]string `cfgrant:"default:0x0:origin"`
+		OriginPtr *Point           `cfgrant:"default:3x4"`
+		Temp      Celsius          `cfgrant:"default:36.6"`
+	}
+
+	os.Args = []string{"configrant.test"}
+	RegisterConverter(parsePoint)
+
+	t.Log("Expect registered converter to be used for fields, slice elements and map keys")
+
+	cfg := &ConvertersConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Origin != (Point{1, 2}) {
+		t.Errorf("Expect field 'Origin' to be equal {1 2}, got %v", cfg.Origin)
+	}
+	if !reflect.DeepEqual(cfg.Path, []Point{{1, 1}, {2, 2}}) {
+		t.Errorf("Expect field 'Path' to be equal [{1 1} {2 2}], got %v", cfg.Path)
+	}
+	if !reflect.DeepEqual(cfg.Named, map[Point]string{{0, 0}: "origin"}) {
+		t.Errorf("Expect field 'Named' to be equal map[{0 0}:origin], got %v", cfg.Named)
+	}
+	if cfg.OriginPtr == nil || *cfg.OriginPtr != (Point{3, 4}) {
+		t.Errorf("Expect field 'OriginPtr' to be equal {3 4}, got %v", cfg.OriginPtr)
+	}
+
+	t.Log("Expect loader converter to take precedence over registered converter")
+
+	failing := New(WithConverter(func(value string) (Point, error) {
+		return Point{}, errors.New("points are disabled")
+	}))
+	var errs Errors
+	if err := failing.Process(&ConvertersConfig{}); !errors.As(err, &errs) || len(errs) != 4 {
+		t.Errorf("Expect errors for 4 point fields, got %v", err)
+	}
+
+	t.Log("Expect loader converter to take precedence over built-in conversion")
+
+	cfg = &ConvertersConfig{}
+	loader := New(WithConverter(func(value string) (Celsius, error) {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "C"), 64)
+		return Celsius(f) + 1, err
+	}))
+	if err := loader.Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Temp != 37.6 {
+		t.Errorf("Expect field 'Temp' to be equal 37.6, got %v", cfg.Temp)
+	}
+}
+
+func TestProcessStandardTypes(t *testing.T) {
+	type StandardConfig struct {
+		Started  time.Time      `cfgrant:"default:2022-01-02T15:04:05Z"`
+		Date     time.Time      `cfgrant:"default:02.01.2022,layout:02.01.2006"`
+		Endpoint *url.URL       `cfgrant:"default:https://example.com/api?v=1"`
+		IP       net.IP         `cfgrant:"default:::1"`
+		Subnet   net.IPNet      `cfgrant:"default:10.0.0.0/8"`
+		Subnets  []net.IPNet    `cfgrant:"default:10.0.0.0/8;192.168.0.0/16"`
+		Filter   *regexp.Regexp `cfgrant:"default:^api/v[0-9]+$"`
+		Mode     os.FileMode    `cfgrant:"default:0640"`
+	}
+
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect standard library types to be parsed")
+
+	cfg := &StandardConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if !cfg.Started.Equal(time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expect field 'Started' to be equal 2022-01-02T15:04:05Z, got %s", cfg.Started)
+	}
+	if !cfg.Date.Equal(time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expect field 'Date' to be equal 2022-01-02, got %s", cfg.Date)
+	}
+	if cfg.Endpoint == nil || cfg.Endpoint.String() != "https://example.com/api?v=1" {
+		t.Errorf("Expect field 'Endpoint' to be equal https://example.com/api?v=1, got %v", cfg.Endpoint)
+	}
+	if !cfg.IP.Equal(net.IPv6loopback) {
+		t.Errorf("Expect field 'IP' to be equal ::1, got %s", cfg.IP)
+	}
+	if cfg.Subnet.String() != "10.0.0.0/8" {
+		t.Errorf("Expect field 'Subnet' to be equal 10.0.0.0/8, got %s", cfg.Subnet.String())
+	}
+	if len(cfg.Subnets) != 2 || cfg.Subnets[1].String() != "192.168.0.0/16" {
+		t.Errorf("Expect field 'Subnets' to be equal [10.0.0.0/8 192.168.0.0/16], got %v", cfg.Subnets)
+	}
+	if cfg.Filter == nil || !cfg.Filter.MatchString("api/v2") {
+		t.Errorf("Expect field 'Filter' to match api/v2, got %v", cfg.Filter)
+	}
+	if cfg.Mode != 0o640 {
+		t.Errorf("Expect field 'Mode' to be equal 0640, got %o", cfg.Mode)
+	}
+
+	t.Log("Expect errors to mention expected format")
+
+	type InvalidStandardConfig struct {
+		Date   time.Time   `cfgrant:"default:2022-01-02,layout:02.01.2006"`
+		IP     net.IP      `cfgrant:"default:localhost"`
+		Subnet net.IPNet   `cfgrant:"default:10.0.0.0"`
+		Mode   os.FileMode `cfgrant:"default:rw-r--r--"`
+	}
+	err := Process(&InvalidStandardConfig{})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatalf("Expect 4 field errors, got %v", err)
+	}
+	for i, format := range []string{"02.01.2006", "IPv4", "CIDR", "octal"} {
+		if !strings.Contains(errs[i].Error(), format) {
+			t.Errorf("Expect error for field '%s' to mention %s, got %v", errs[i].Path, format, errs[i])
+		}
+	}
+}
+
+type UpstreamConfig struct {
+	Host    string        `cfgrant:"env:HOST,arg:host,required"`
+	Port    int           `cfgrant:"env:PORT,arg:port,default:80,max:65535"`
+	Timeout time.Duration `cfgrant:"env:TIMEOUT,default:5s"`
+}
+
+type DatabaseConfig struct {
+	DSN      string `cfgrant:"env:DSN,key:dsn"`
+	MaxConns int    `cfgrant:"env:MAX_CONNS,key:maxConns,default:10"`
+}
+
+type CollectionsConfig struct {
+	Upstreams []UpstreamConfig          `cfgrant:"env:UPSTREAMS,arg:upstreams,key:upstreams"`
+	Databases map[string]DatabaseConfig `cfgrant:"env:DATABASES,key:databases"`
+	Replicas  []*DatabaseConfig         `cfgrant:"key:replicas"`
+}
+
+func TestProcessCollections(t *testing.T) {
+	t.Log("Expect elements to be discovered from indexed environment variables and arguments")
+
+	t.Setenv("UPSTREAMS_0_HOST", "first.example.com")
+	t.Setenv("UPSTREAMS_1_HOST", "second.example.com")
+	t.Setenv("UPSTREAMS_1_PORT", "8080")
+	t.Setenv("DATABASES_PRIMARY_DSN", "postgres://primary")
+	os.Args = []string{"configrant.test", "--upstreams.1.port=9090", "--upstreams.2.host", "third.example.com"}
+
+	cfg := &CollectionsConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expectedUpstreams := []UpstreamConfig{
+		{Host: "first.example.com", Port: 80, Timeout: 5 * time.Second},
+		{Host: "second.example.com", Port: 9090, Timeout: 5 * time.Second},
+		{Host: "third.example.com", Port: 80, Timeout: 5 * time.Second},
+	}
+	if !reflect.DeepEqual(cfg.Upstreams, expectedUpstreams) {
+		t.Errorf("Expect field 'Upstreams' to be equal %v, got %v", expectedUpstreams, cfg.Upstreams)
+	}
+	expectedDatabases := map[string]DatabaseConfig{"primary": {DSN: "postgres://primary", MaxConns: 10}}
+	if !reflect.DeepEqual(cfg.Databases, expectedDatabases) {
+		t.Errorf("Expect field 'Databases' to be equal %v, got %v", expectedDatabases, cfg.Databases)
+	}
+	if cfg.Replicas != nil {
+		t.Errorf("Expect field 'Replicas' to stay nil, got %v", cfg.Replicas)
+	}
+
+	t.Log("Expect elements to be taken from configuration file sections")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+upstreams:
+  - host: file.example.com
+    port: 8443
+databases:
+  analytics:
+    dsn: postgres://analytics
+    maxConns: 3
+replicas:
+  - dsn: postgres://replica
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	os.Args = []string{"configrant.test"}
+	cfg = &CollectionsConfig{}
+	if err := New(WithSources(File(path), Defaults())).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if len(cfg.Upstreams) != 1 || cfg.Upstreams[0].Host != "file.example.com" || cfg.Upstreams[0].Port != 8443 {
+		t.Errorf("Expect field 'Upstreams' to hold file.example.com:8443, got %v", cfg.Upstreams)
+	}
+	if db := cfg.Databases["analytics"]; db.DSN != "postgres://analytics" || db.MaxConns != 3 {
+		t.Errorf("Expect field 'Databases' to hold analytics database, got %v", cfg.Databases)
+	}
+	if len(cfg.Replicas) != 1 || cfg.Replicas[0].DSN != "postgres://replica" || cfg.Replicas[0].MaxConns != 10 {
+		t.Errorf("Expect field 'Replicas' to hold replica with default max connections, got %v", cfg.Replicas)
+	}
+
+	t.Log("Expect element field errors to be reported with element path")
+
+	os.Unsetenv("DATABASES_PRIMARY_DSN")
+	t.Setenv("UPSTREAMS_0_HOST", "")
+	t.Setenv("UPSTREAMS_0_PORT", "70000")
+	t.Setenv("UPSTREAMS_1_TIMEOUT", "soon")
+	os.Args = []string{"configrant.test"}
+	err := Process(&CollectionsConfig{})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect field errors, got %v", err)
+	}
+	paths := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		paths[i] = fieldErr.Path
+	}
+	expectedPaths := []string{"Upstreams[0].Host", "Upstreams[1].Timeout", "Upstreams[0].Port"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expect errors for %v, got %v", expectedPaths, err)
+	}
+
+	t.Log("Expect element fields to be validated")
+
+	t.Setenv("UPSTREAMS_0_HOST", "first.example.com")
+	os.Unsetenv("UPSTREAMS_1_TIMEOUT")
+	err = Process(&CollectionsConfig{})
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != "Upstreams[0].Port" {
+		t.Errorf("Expect validation error for field 'Upstreams[0].Port', got %v", err)
+	}
+
+	t.Log("Expect map keys with underscores to be split by element variable names")
+
+	env := map[string]string{
+		"DATABASES_READ_REPLICA_DSN":       "postgres://replica",
+		"DATABASES_READ_REPLICA_MAX_CONNS": "3",
+		"DATABASES_UNRELATED":              "x",
+	}
+	cfg = &CollectionsConfig{}
+	if err := New(WithArgs(), WithEnvMap(env)).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expectedDatabases = map[string]DatabaseConfig{"read_replica": {DSN: "postgres://replica", MaxConns: 3}}
+	if !reflect.DeepEqual(cfg.Databases, expectedDatabases) {
+		t.Errorf("Expect databases %+v, got %+v", expectedDatabases, cfg.Databases)
+	}
+
+	type AmbiguousConfig struct {
+		Databases map[string]struct {
+			DSN        string `cfgrant:"env:DSN"`
+			ReplicaDSN string `cfgrant:"env:REPLICA_DSN"`
+		} `cfgrant:"env:DATABASES"`
+	}
+	err = New(WithArgs(), WithEnvMap(map[string]string{"DATABASES_READ_REPLICA_DSN": "x"})).Process(&AmbiguousConfig{})
+	if !errors.As(err, &errs) || errs[0].Path != "Databases" || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expect ambiguous variable error, got %v", err)
+	}
+
+	t.Log("Expect out-of-range and gapped element indices to be reported instead of allocated")
+
+	for _, args := range [][]string{{"--upstreams.9223372036854775807.host=x"}, {"--upstreams.0.host=x", "--upstreams.5000000.host=y"}} {
+		err = New(WithArgs(args...), WithEnvMap(nil)).Process(&CollectionsConfig{})
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != "Upstreams" || !strings.Contains(err.Error(), "gap") {
+			t.Errorf("Expect gap error for field 'Upstreams' with %v, got %v", args, err)
+		}
+	}
+
+	t.Log("Expect elements which exist before processing to be kept")
+
+	existing := &CollectionsConfig{
+		Upstreams: []UpstreamConfig{{Host: "a"}, {Host: "b"}, {Host: "c"}},
+		Databases: map[string]DatabaseConfig{"legacy": {DSN: "postgres://legacy"}},
+	}
+	env = map[string]string{"UPSTREAMS_0_HOST": "z", "UPSTREAMS_3_HOST": "d", "DATABASES_PRIMARY_DSN": "postgres://primary"}
+	if err := New(WithArgs(), WithEnvMap(env)).Process(existing); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	hosts := make([]string, 0, len(existing.Upstreams))
+	for _, upstream := range existing.Upstreams {
+		hosts = append(hosts, upstream.Host)
+	}
+	if expected := []string{"z", "b", "c", "d"}; !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Expect upstream hosts to be %v, got %v", expected, hosts)
+	}
+	if len(existing.Databases) != 2 || existing.Databases["legacy"].MaxConns != 10 || existing.Databases["primary"].DSN != "postgres://primary" {
+		t.Errorf("Expect existing database to be kept and maintained, got %+v", existing.Databases)
+	}
+
+	t.Log("Expect boolean element arguments to take no value")
+
+	type TLSUpstream struct {
+		Host   string `cfgrant:"arg:host"`
+		TLS    bool   `cfgrant:"arg:tls"`
+		Routes []struct {
+			Cache bool   `cfgrant:"arg:cache"`
+			Path  string `cfgrant:"arg:path"`
+		} `cfgrant:"arg:routes"`
+	}
+	type TLSConfig struct {
+		Ups []TLSUpstream `cfgrant:"arg:ups"`
+	}
+	tlsCfg := &TLSConfig{}
+	args := []string{"--ups.0.tls", "--ups.0.host", "x", "--ups.0.routes.0.cache", "--ups.0.routes.0.path", "/", "--no-ups.1.tls"}
+	if err := New(WithArgs(args...), WithEnvMap(nil), WithStrictArgs()).Process(tlsCfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if len(tlsCfg.Ups) != 2 || !tlsCfg.Ups[0].TLS || tlsCfg.Ups[0].Host != "x" || tlsCfg.Ups[1].TLS ||
+		len(tlsCfg.Ups[0].Routes) != 1 || !tlsCfg.Ups[0].Routes[0].Cache || tlsCfg.Ups[0].Routes[0].Path != "/" {
+		t.Errorf("Expect boolean element flags to be set without values, got %+v", *tlsCfg)
+	}
+}
+
+func TestProcessNestedCollections(t *testing.T) {
+	type NestedConfig struct {
+		Point   [3]int              `cfgrant:"default:1;2;3"`
+		Matrix  [][]string          `cfgrant:"default:a|b;c|d|e"`
+		Labels  []map[string]string `cfgrant:"default:env:prod|tier:web;env:dev"`
+		Ports   map[string][]int    `cfgrant:"default:http:80|8080;https:443"`
+		Windows [][2]time.Duration  `cfgrant:"key:windows"`
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+windows:
+  - [1s, 5s]
+  - [10s, 1m]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	os.Args = []string{"configrant.test", "--config", path}
+
+	t.Log("Expect arrays and multi-level collections to be split by delimiters hierarchy")
+
+	cfg := &NestedConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Point != [3]int{1, 2, 3} {
+		t.Errorf("Expect field 'Point' to be equal [1 2 3], got %v", cfg.Point)
+	}
+	if expected := [][]string{{"a", "b"}, {"c", "d", "e"}}; !reflect.DeepEqual(cfg.Matrix, expected) {
+		t.Errorf("Expect field 'Matrix' to be equal %v, got %v", expected, cfg.Matrix)
+	}
+	if expected := []map[string]string{{"env": "prod", "tier": "web"}, {"env": "dev"}}; !reflect.DeepEqual(cfg.Labels, expected) {
+		t.Errorf("Expect field 'Labels' to be equal %v, got %v", expected, cfg.Labels)
+	}
+	if expected := map[string][]int{"http": {80, 8080}, "https": {443}}; !reflect.DeepEqual(cfg.Ports, expected) {
+		t.Errorf("Expect field 'Ports' to be equal %v, got %v", expected, cfg.Ports)
+	}
+	if expected := [][2]time.Duration{{time.Second, 5 * time.Second}, {10 * time.Second, time.Minute}}; !reflect.DeepEqual(cfg.Windows, expected) {
+		t.Errorf("Expect field 'Windows' to be equal %v, got %v", expected, cfg.Windows)
+	}
+
+	t.Log("Expect custom delimiters hierarchy to be used")
+
+	os.Args = []string{"configrant.test"}
+	type DelimitedConfig struct {
+		Matrix [][]int `cfgrant:"default:1/2 3/4"`
+	}
+	delimited := &DelimitedConfig{}
+	if err := New(WithDelimiters(" ", "/")).Process(delimited); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if expected := [][]int{{1, 2}, {3, 4}}; !reflect.DeepEqual(delimited.Matrix, expected) {
+		t.Errorf("Expect field 'Matrix' to be equal %v, got %v", expected, delimited.Matrix)
+	}
+
+	t.Log("Expect empty and duplicate delimiters to be rejected")
+
+	for _, delimiters := range [][]string{{""}, {";", ""}, {";", "|", ";"}} {
+		loader := New(WithDelimiters(delimiters...))
+		if err := loader.Process(&DelimitedConfig{}); err == nil || !strings.HasPrefix(err.Error(), "configrant: delimiter") {
+			t.Errorf("Expect Process to reject delimiters %q, got %v", delimiters, err)
+		}
+		if err := loader.Check(&DelimitedConfig{}); err == nil {
+			t.Errorf("Expect Check to reject delimiters %q", delimiters)
+		}
+	}
+
+	t.Log("Expect element count mismatch and too deep nesting to be reported")
+
+	type InvalidNestedConfig struct {
+		Point [3]int      `cfgrant:"default:1;2"`
+		Pair  [2]int      `cfgrant:"default:1;x"`
+		Cube  [][][][]int `cfgrant:"default:1"`
+	}
+	err := Process(&InvalidNestedConfig{})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expect 3 field errors, got %v", err)
+	}
+	for i, message := range []string{"exactly 3 elements", "element 1", "delimiters hierarchy"} {
+		if !strings.Contains(errs[i].Error(), message) {
+			t.Errorf("Expect error for field '%s' to mention %q, got %v", errs[i].Path, message, errs[i])
+		}
+	}
+}
+
+func TestProcessSeparators(t *testing.T) {
+	type SeparatedConfig struct {
+		Endpoints map[string]string `cfgrant:"default:api:http://localhost:8080;web:https://example.com"`
+		Hosts     []string          `cfgrant:"sep:',',default:'a, b,c'"`
+		Headers   map[string]string `cfgrant:"kvsep:=,default:Accept=application/json;X-Ratio=1:2"`
+		Items     []string          `cfgrant:"default:a\\;b;c"`
+		Greeting  string            `cfgrant:"default:hello\\, world"`
+		Files     []string          `cfgrant:"positional"`
+		Patterns  []string          `cfgrant:"key:patterns"`
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("patterns: ['a;b', 'c']\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	os.Args = []string{"configrant.test", "--config", path, "first;file", "second"}
+
+	t.Log("Expect per-field separators and escaped delimiters to be respected")
+
+	cfg := &SeparatedConfig{}
+	if err := Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if expected := map[string]string{"api": "http://localhost:8080", "web": "https://example.com"}; !reflect.DeepEqual(cfg.Endpoints, expected) {
+		t.Errorf("Expect field 'Endpoints' to be equal %v, got %v", expected, cfg.Endpoints)
+	}
+	if expected := []string{"a", " b", "c"}; !reflect.DeepEqual(cfg.Hosts, expected) {
+		t.Errorf("Expect field 'Hosts' to be equal %q, got %q", expected, cfg.Hosts)
+	}
+	if expected := map[string]string{"Accept": "application/json", "X-Ratio": "1:2"}; !reflect.DeepEqual(cfg.Headers, expected) {
+		t.Errorf("Expect field 'Headers' to be equal %v, got %v", expected, cfg.Headers)
+	}
+	if expected := []string{"a;b", "c"}; !reflect.DeepEqual(cfg.Items, expected) {
+		t.Errorf("Expect field 'Items' to be equal %q, got %q", expected, cfg.Items)
+	}
+	if cfg.Greeting != "hello, world" {
+		t.Errorf("Expect field 'Greeting' to be equal 'hello, world', got %q", cfg.Greeting)
+	}
+	if expected := []string{"first;file", "second"}; !reflect.DeepEqual(cfg.Files, expected) {
+		t.Errorf("Expect field 'Files' to be equal %q, got %q", expected, cfg.Files)
+	}
+	if expected := []string{"a;b", "c"}; !reflect.DeepEqual(cfg.Patterns, expected) {
+		t.Errorf("Expect field 'Patterns' to be equal %q, got %q", expected, cfg.Patterns)
+	}
+
+	t.Log("Expect malformed tags to be reported")
+
+	type MalformedConfig struct {
+		Quote    string `cfgrant:"env:QUOTE,default:'unterminated"`
+		Flag     bool   `cfgrant:"required:true"`
+		Missing  string `cfgrant:"env"`
+		Empty    string `cfgrant:"env:EMPTY,,default:x"`
+		Trailing string `cfgrant:"default:'x' y"`
+		Valid    string `cfgrant:"default:ok"`
+	}
+	os.Args = []string{"configrant.test"}
+	malformed := &MalformedConfig{}
+	err := Process(malformed)
+	var errs Errors
+	if !errors.Is(err, ErrInvalidTag) || !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatalf("Expect 5 invalid tag errors, got %v", err)
+	}
+	for i, path := range []string{"Quote", "Flag", "Missing", "Empty", "Trailing"} {
+		if errs[i].Path != path {
+			t.Errorf("Expect error for field '%s', got %v", path, errs[i])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	type CheckedSubstruct struct {
+		Name string `cfgrant:"arg:name"`
+	}
+	type CheckedConfig struct {
+		Unknown   string            `cfgrant:"env:UNKNOWN,option:value"`
+		Duplicate string            `cfgrant:"env:A,env:B"`
+		EmptyEnv  string            `cfgrant:"env:"`
+		Malformed string            `cfgrant:"default:'x"`
+		Channel   chan int          `cfgrant:"env:CHANNEL"`
+		Name      string            `cfgrant:"arg:--name"`
+		Sub       CheckedSubstruct  `cfgrant:"key:sub"`
+		Other     string            `cfgrant:"key:SUB.name"`
+		Upstreams []UpstreamConfig  `cfgrant:"arg:upstreams"`
+		Valid     map[string]string `cfgrant:"env:VALID,default:a:1"`
+		Mirror    string            `cfgrant:"env:VALID"`
+	}
+
+	t.Log("Expect every problem of configuration type to be reported")
+
+	err := Check(&CheckedConfig{})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expect field errors, got %v", err)
+	}
+	expected := []struct {
+		path   string
+		target error
+	}{
+		{"Unknown", ErrInvalidTag},
+		{"Duplicate", ErrInvalidTag},
+		{"EmptyEnv", ErrInvalidTag},
+		{"Malformed", ErrInvalidTag},
+		{"Channel", ErrUnsupportedType},
+		{"Sub.Name", ErrConflictingName},
+		{"Other", ErrConflictingName},
+		{"Mirror", ErrConflictingName},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expect %d problems, got %v", len(expected), err)
+	}
+	for i, e := range expected {
+		if errs[i].Path != e.path || !errors.Is(errs[i], e.target) {
+			t.Errorf("Expect problem %v for field '%s', got %v", e.target, e.path, errs[i])
+		}
+	}
+
+	t.Log("Expect strict Process to fail before any source is consulted")
+
+	t.Setenv("UNKNOWN", "value")
+	os.Args = []string{"configrant.test"}
+	cfg := &CheckedConfig{}
+	if err := New(WithStrictTags()).Process(cfg); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("Expect strict Process to fail with ErrInvalidTag, got %v", err)
+	}
+	if cfg.Unknown != "" {
+		t.Errorf("Expect field 'Unknown' to stay empty, got %s", cfg.Unknown)
+	}
+
+	t.Log("Expect valid configuration to pass the check")
+
+	if err := Check(&CollectionsConfig{}); err != nil {
+		t.Errorf("Expect no problems, got %v", err)
+	}
+}
+
+func TestLoaderConcurrency(t *testing.T) {
+	type ConcurrentConfig struct {
+		Name    string `cfgrant:"arg:name,env:NAME"`
+		Retries int    `cfgrant:"arg:retries,env:RETRIES,default:1"`
+		Config  string `cfgrant:"key:config"`
+	}
+
+	dir := t.TempDir()
+	os.Args = []string{"configrant.test", "--name=global"}
+
+	t.Log("Expect loaders to use own arguments and environment concurrently")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := filepath.Join(dir, fmt.Sprintf("config-%d.yaml", i))
+			if err := os.WriteFile(path, []byte(fmt.Sprintf("config: file-%d\n", i)), 0o600); err != nil {
+				errs <- err
+				return
+			}
+			env := map[string]string{"RETRIES": strconv.Itoa(i), ConfigFileEnv: path}
+			loader := New(
+				WithArgs(fmt.Sprintf("--name=loader-%d", i)),
+				WithLookupEnv(func(name string) (string, bool) {
+					value, ok := env[name]
+					return value, ok
+				}),
+			)
+			cfg := &ConcurrentConfig{}
+			if err := loader.Process(cfg); err != nil {
+				errs <- err
+				return
+			}
+			expected := ConcurrentConfig{Name: fmt.Sprintf("loader-%d", i), Retries: i, Config: fmt.Sprintf("file-%d", i)}
+			if *cfg != expected {
+				errs <- fmt.Errorf("expect %+v, got %+v", expected, *cfg)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	t.Log("Expect the same loader to be shared by goroutines")
+
+	path := filepath.Join(dir, "shared.yaml")
+	if err := os.WriteFile(path, []byte("config: shared\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	loader := New(WithArgs("--retries", "3", "--config", path), WithLookupEnv(func(string) (string, bool) { return "", false }))
+	var shared sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		shared.Add(1)
+		go func() {
+			defer shared.Done()
+			cfg := &ConcurrentConfig{}
+			if err := loader.Process(cfg); err != nil {
+				t.Error(err)
+				return
+			}
+			if cfg.Retries != 3 || cfg.Config != "shared" || cfg.Name != "" {
+				t.Errorf("Expect retries 3 and config from shared file only, got %+v", *cfg)
+			}
+		}()
+	}
+	shared.Wait()
+
+	t.Log("Expect Binder sources to be bound for each Process call")
+
+	var binds int32
+	loader = New(WithSources(bindingSource{binds: &binds}), WithArgs())
+	names := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		shared.Add(1)
+		go func() {
+			defer shared.Done()
+			cfg := &ConcurrentConfig{}
+			if err := loader.Process(cfg); err != nil {
+				t.Error(err)
+			}
+			names <- cfg.Name
+		}()
+	}
+	shared.Wait()
+	close(names)
+	seen := make(map[string]bool)
+	for name := range names {
+		seen[name] = true
+	}
+	if binds != 10 || len(seen) != 10 || seen[""] {
+		t.Errorf("Expect each call to use its own bound source, got %d binds and names %v", binds, seen)
+	}
+}
+
+func TestLoaderEnvironment(t *testing.T) {
+	type EnvironmentConfig struct {
+		Host      string           `cfgrant:"env:HOST,default:localhost"`
+		Port      int              `cfgrant:"env:PORT"`
+		Password  string           `cfgrant:"env:PASSWORD"`
+		Upstreams []UpstreamConfig `cfgrant:"env:UPSTREAMS"`
+	}
+
+	os.Args = []string{"configrant.test"}
+	t.Setenv("HOST", "process.example.com")
+
+	t.Log("Expect environment to be taken from map instead of process environment")
+
+	cfg := &EnvironmentConfig{}
+	env := map[string]string{"PORT": "8080", "UPSTREAMS_0_HOST": "upstream.example.com"}
+	if err := New(WithEnvMap(env)).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 || len(cfg.Upstreams) != 1 || cfg.Upstreams[0].Host != "upstream.example.com" {
+		t.Errorf("Expect values from map only, got %+v", *cfg)
+	}
+
+	t.Log("Expect environment to be taken from os.Environ-style slice, the last value wins")
+
+	cfg = &EnvironmentConfig{}
+	if err := New(WithEnviron([]string{"PORT=1", "HOST=child.example.com", "PORT=2", "INVALID"})).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Host != "child.example.com" || cfg.Port != 2 {
+		t.Errorf("Expect host child.example.com and port 2, got %+v", *cfg)
+	}
+
+	t.Log("Expect .env file to complement environment")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `
+# database settings
+export PORT=5432
+HOST=file.example.com
+PASSWORD="p@ss \"word\"" # quoted
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	cfg = &EnvironmentConfig{}
+	if err := New(WithEnvFile(path)).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Host != "process.example.com" || cfg.Port != 5432 || cfg.Password != `p@ss "word"` {
+		t.Errorf("Expect host from process environment, port and password from file, got %+v", *cfg)
+	}
+
+	t.Log("Expect malformed .env file to be reported")
+
+	if err := os.WriteFile(path, []byte("PORT 5432\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := New(WithEnvFile(path)).Process(&EnvironmentConfig{}); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expect error mentioning line 1, got %v", err)
+	}
+}
+
+func TestProcessWithReport(t *testing.T) {
+	type ReportConfig struct {
+		Host    string `cfgrant:"env:HOST,arg:host,default:localhost"`
+		Port    int    `cfgrant:"env:PORT,default:8080"`
+		Name    string `cfgrant:"env:NAME"`
+		Timeout time.Duration
+	}
+
+	os.Args = []string{"configrant.test"}
+	env := map[string]string{"HOST": "env.example.com", "PORT": "9090"}
+	cfg := &ReportConfig{Timeout: 5 * time.Second}
+
+	t.Log("Expect report to list winning source and shadowed ones for each field")
+
+	report, err := New(WithArgs("--host", "arg.example.com"), WithEnvMap(env)).ProcessWithReport(cfg)
+	if err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if len(report.Fields) != 4 {
+		t.Fatalf("Expect 4 fields in report, got %d", len(report.Fields))
+	}
+
+	host, ok := report.Lookup("Host")
+	if !ok || host.Source != "arg" || host.Value != "arg.example.com" || host.Env != "HOST" || !reflect.DeepEqual(host.Args, []string{"host"}) {
+		t.Errorf("Expect host from args, got %+v", host)
+	}
+	expectedShadowed := []SourceValue{{Source: "env", Value: "env.example.com"}, {Source: "default", Value: "localhost"}}
+	if !reflect.DeepEqual(host.Shadowed, expectedShadowed) {
+		t.Errorf("Expect env and default to be shadowed, got %+v", host.Shadowed)
+	}
+	if port, _ := report.Lookup("Port"); port.Source != "env" || port.Value != "9090" || len(port.Shadowed) != 1 {
+		t.Errorf("Expect port from env shadowing default, got %+v", port)
+	}
+	if name, _ := report.Lookup("Name"); name.Source != "" || name.Value != "" {
+		t.Errorf("Expect name to have no source, got %+v", name)
+	}
+	if timeout, _ := report.Lookup("Timeout"); timeout.Source != "literal" || timeout.Value != "5s" {
+		t.Errorf("Expect timeout from literal, got %+v", timeout)
+	}
+
+	t.Log("Expect report to be rendered as table and JSON")
+
+	table := report.String()
+	if !strings.HasPrefix(table, "FIELD") || !strings.Contains(table, `env="env.example.com", default="localhost"`) {
+		t.Errorf("Unexpected table:\n%s", table)
+	}
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to encode report: %v", err)
+	}
+	if !strings.Contains(string(encoded), `{"path":"Port","source":"env","value":"9090","env":"PORT","key":"Port","shadowed":[{"source":"default","value":"8080"}]}`) {
+		t.Errorf("Unexpected JSON: %s", encoded)
+	}
+}
+
+func TestSecretFields(t *testing.T) {
+	type SecretConfig struct {
+		Password string         `cfgrant:"env:PASSWORD,secret,default:changeme"`
+		Pin      int            `cfgrant:"env:PIN,secret,max:9999"`
+		Token    Secret[string] `cfgrant:"env:TOKEN"`
+		Ports    []int          `cfgrant:"env:PORTS,secret"`
+	}
+
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect secret values to be maintained and Secret wrapper to hide its value")
+
+	cfg := &SecretConfig{}
+	env := map[string]string{"PASSWORD": "hunter2", "PIN": "1234", "TOKEN": "s3cr3t", "PORTS": "80;443"}
+	report, err := New(WithEnvMap(env)).ProcessWithReport(cfg)
+	if err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Password != "hunter2" || cfg.Pin != 1234 || cfg.Token.Value() != "s3cr3t" {
+		t.Errorf("Expect secret values to be maintained, got %v %v %v", cfg.Password, cfg.Pin, cfg.Token.Value())
+	}
+	for _, rendered := range []string{fmt.Sprint(cfg.Token), fmt.Sprintf("%+v %#v %s %q %d", *cfg, cfg.Token, cfg.Token, cfg.Token, cfg.Token)} {
+		if strings.Contains(rendered, "s3cr3t") {
+			t.Errorf("Expect token to be redacted, got %s", rendered)
+		}
+	}
+	if encoded, _ := json.Marshal(cfg.Token); string(encoded) != `"******"` {
+		t.Errorf("Expect token to be encoded redacted, got %s", encoded)
+	}
+
+	t.Log("Expect secret values to be redacted in report and usage")
+
+	if password, _ := report.Lookup("Password"); password.Value != RedactedValue || password.Shadowed[0].Value != RedactedValue {
+		t.Errorf("Expect password to be redacted in report, got %+v", password)
+	}
+	if token, _ := report.Lookup("Token"); token.Value != RedactedValue {
+		t.Errorf("Expect token to be redacted in report, got %+v", token)
+	}
+	var buf bytes.Buffer
+	if err := Usage(&SecretConfig{}, &buf); err != nil || strings.Contains(buf.String(), "changeme") {
+		t.Errorf("Expect default password to be redacted in usage, got %v\n%s", err, buf.String())
+	}
+
+	t.Log("Expect fields of secret substructure and collection to be secret")
+
+	type SecretDB struct {
+		Password string `cfgrant:"env:DB_PASSWORD"`
+	}
+	type NestedSecretConfig struct {
+		DB      SecretDB   `cfgrant:"secret"`
+		Replica []SecretDB `cfgrant:"secret"`
+	}
+	nested := &NestedSecretConfig{Replica: []SecretDB{{Password: "replicasecret"}}}
+	report, err = New(WithEnvMap(map[string]string{"DB_PASSWORD": "topsecret"})).ProcessWithReport(nested)
+	if err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if password, _ := report.Lookup("DB.Password"); password.Value != RedactedValue {
+		t.Errorf("Expect nested password to be redacted in report, got %+v", password)
+	}
+	environ, err := DumpEnviron(nested)
+	if err != nil || strings.Contains(strings.Join(environ, " "), "secret") {
+		t.Errorf("Expect nested passwords to be redacted in dump, got %v %v", err, environ)
+	}
+
+	t.Log("Expect secret values to be redacted in errors")
+
+	env = map[string]string{"PORTS": "80;s3cr3t"}
+	err = New(WithEnvMap(env)).Process(&SecretConfig{})
+	if err == nil || strings.Contains(err.Error(), "s3cr3t") || !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Expect redacted syntax error, got %v", err)
+	}
+	env = map[string]string{"PIN": "98765"}
+	err = New(WithEnvMap(env)).Process(&SecretConfig{})
+	if err == nil || strings.Contains(err.Error(), "98765") || !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expect redacted validation error, got %v", err)
+	}
+}
+
+func TestProcessValueFiles(t *testing.T) {
+	type ValueFileConfig struct {
+		Password string `cfgrant:"env:DB_PASSWORD"`
+		Token    string `cfgrant:"env:TOKEN,file:token"`
+		User     string `cfgrant:"file:missing,default:admin"`
+	}
+
+	// value files given by file option are relative to working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	passwordPath := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(passwordPath, []byte("p@ssword\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write value file: %v", err)
+	}
+	if err := os.WriteFile("token", []byte("t0ken\r\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write value file: %v", err)
+	}
+
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect values to be read from file pointed by _FILE variable and file option with trailing newline trimmed")
+
+	cfg := &ValueFileConfig{}
+	if err := New(WithEnvMap(map[string]string{"DB_PASSWORD_FILE": passwordPath})).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Password != "p@ssword" || cfg.Token != "t0ken" || cfg.User != "admin" {
+		t.Errorf("Expect password and token from files and default user, got %+v", *cfg)
+	}
+
+	t.Log("Expect variable itself to win over files")
+
+	cfg = &ValueFileConfig{}
+	env := map[string]string{"DB_PASSWORD": "direct", "DB_PASSWORD_FILE": passwordPath, "TOKEN": "env-token"}
+	if err := New(WithEnvMap(env)).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Password != "direct" || cfg.Token != "env-token" {
+		t.Errorf("Expect values from variables, got %+v", *cfg)
+	}
+
+	t.Log("Expect unreadable and oversized files to be reported")
+
+	bigPath := filepath.Join(dir, "big")
+	if err := os.WriteFile(bigPath, bytes.Repeat([]byte("x"), MaxValueFileSize+1), 0o600); err != nil {
+		t.Fatalf("Failed to write value file: %v", err)
+	}
+	for _, path := range []string{filepath.Join(dir, "nonexistent"), dir, bigPath} {
+		err := New(WithEnvMap(map[string]string{"DB_PASSWORD_FILE": path})).Process(&ValueFileConfig{})
+		var errs Errors
+		if !errors.As(err, &errs) || errs[0].Path != "Password" || !strings.Contains(err.Error(), "value file") {
+			t.Errorf("Expect value file error for %s, got %v", path, err)
+		}
+	}
+
+	t.Log("Expect file option to work with any sources order and to be reported as value file")
+
+	for _, sources := range [][]Source{{Args(), Defaults()}, {Args()}, {Defaults(), Env()}} {
+		cfg = &ValueFileConfig{}
+		report, err := New(WithSources(sources...), WithArgs(), WithEnvMap(nil)).ProcessWithReport(cfg)
+		if err != nil {
+			t.Fatalf("Error occured during parsing %s", err.Error())
+		}
+		if token, _ := report.Lookup("Token"); cfg.Token != "t0ken" || token.Source != "value-file" || token.Value != "t0ken" {
+			t.Errorf("Expect token from value file, got %+v", token)
+		}
+	}
+	report, err := New(WithArgs("--token=arg-token"), WithEnvMap(nil), WithAutoArgs()).ProcessWithReport(&ValueFileConfig{})
+	if err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	expected := []SourceValue{{Source: "value-file", Value: "t0ken"}}
+	if token, _ := report.Lookup("Token"); token.Source != "arg" || !reflect.DeepEqual(token.Shadowed, expected) {
+		t.Errorf("Expect value file to be shadowed by argument, got %+v", token)
+	}
+}
+
+func TestProcessExpansion(t *testing.T) {
+	type ServerConfig struct {
+		Host string `cfgrant:"env:HOST,default:localhost"`
+		Port int    `cfgrant:"env:PORT,default:3000"`
+	}
+	type ExpansionConfig struct {
+		Server   ServerConfig
+		URL      string `cfgrant:"env:URL,expand,default:http://${Server.Host}:${Server.Port}/${API_PREFIX:-api}"`
+		Price    string `cfgrant:"expand,default:$$5 for ${Server.Host}"`
+		Raw      string `cfgrant:"default:${Server.Host}"`
+		Endpoint string `cfgrant:"default:${URL}/v1"`
+	}
+
+	os.Args = []string{"configrant.test"}
+
+	t.Log("Expect references to fields and environment variables to be expanded in fields tagged with expand option")
+
+	cfg := &ExpansionConfig{}
+	if err := New(WithEnvMap(map[string]string{"PORT": "8080"})).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.URL != "http://localhost:8080/api" || cfg.Price != "$5 for localhost" || cfg.Raw != "${Server.Host}" {
+		t.Errorf("Unexpected expansion result %+v", *cfg)
+	}
+
+	t.Log("Expect all fields to be expanded with WithExpansion option")
+
+	cfg = &ExpansionConfig{}
+	env := map[string]string{"HOST": "example.com", "API_PREFIX": "rest"}
+	if err := New(WithEnvMap(env), WithExpansion()).Process(cfg); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if cfg.Raw != "example.com" || cfg.Endpoint != "http://example.com:3000/rest/v1" {
+		t.Errorf("Unexpected expansion result %+v", *cfg)
+	}
+
+	t.Log("Expect value which references secret field to be redacted in report and dump")
+
+	type SecretReferenceConfig struct {
+		Pass  string `cfgrant:"env:PASS,secret"`
+		URL   string `cfgrant:"env:URL,expand,default:x://u:${Pass}@h"`
+		Proxy string `cfgrant:"env:PROXY,expand,default:${URL}/proxy"`
+		Host  string `cfgrant:"env:HOST,expand,default:h"`
+	}
+	secretCfg := &SecretReferenceConfig{}
+	report, err := New(WithArgs(), WithEnvMap(map[string]string{"PASS": "hunter2"})).ProcessWithReport(secretCfg)
+	if err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	if secretCfg.URL != "x://u:hunter2@h" || secretCfg.Proxy != "x://u:hunter2@h/proxy" {
+		t.Errorf("Expect secret to be expanded, got %+v", *secretCfg)
+	}
+	if strings.Contains(report.String(), "hunter2") {
+		t.Errorf("Expect expanded secret to be redacted in report, got\n%s", report)
+	}
+	dump, err := Dump(secretCfg, FormatEnv)
+	if err != nil {
+		t.Fatalf("Error occured during dump %s", err.Error())
+	}
+	if strings.Contains(string(dump), "hunter2") || !strings.Contains(string(dump), "HOST=h\n") {
+		t.Errorf("Expect expanded secret to be redacted in dump, got\n%s", dump)
+	}
+
+	t.Log("Expect reference cycle and unclosed reference to be reported")
+
+	type CycleConfig struct {
+		A string `cfgrant:"default:${B}"`
+		B string `cfgrant:"default:x${A}"`
+		C string `cfgrant:"default:${A"`
+	}
+	err = New(WithEnvMap(nil), WithExpansion()).Process(&CycleConfig{})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 || !errors.Is(err, ErrInvalidExpansion) || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("Expect cycle and unclosed reference errors, got %v", err)
+	}
+}
+
+func TestDump(t *testing.T) {
+	type DumpConfig struct {
+		Timeout   time.Duration    `cfgrant:"env:TIMEOUT,arg:timeout"`
+		Tags      []string         `cfgrant:"env:TAGS,arg:tags"`
+		Limits    map[string]int   `cfgrant:"env:LIMITS,arg:limits"`
+		Motd      string           `cfgrant:"env:MOTD,arg:motd"`
+		Verbose   bool             `cfgrant:"env:VERBOSE,short:-v"`
+		Password  string           `cfgrant:"env:PASSWORD,arg:password,secret"`
+		Upstreams []UpstreamConfig `cfgrant:"env:UPSTREAMS,arg:upstreams"`
+		Files     []string         `cfgrant:"positional"`
+	}
+
+	cfg := &DumpConfig{
+		Timeout:   7 * time.Second,
+		Tags:      []string{"a", "b;c"},
+		Limits:    map[string]int{"x": 1, "y": 2},
+		Motd:      `say "hi" # twice`,
+		Verbose:   true,
+		Password:  "hunter2",
+		Upstreams: []UpstreamConfig{{Host: "one.example.com", Port: 80, Timeout: time.Second}},
+		Files:     []string{"a.txt", "b.txt"},
+	}
+
+	t.Log("Expect args to be encoded the way they are parsed and to reproduce configuration")
+
+	args, err := DumpArgs(cfg)
+	if err != nil {
+		t.Fatalf("Error occured during dump %s", err.Error())
+	}
+	expectedArgs := []string{
+		"--timeout=7s", `--tags=a;b\;c`, "--limits=x:1;y:2", `--motd=say "hi" # twice`, "-v=true", "--password=******",
+		"--upstreams.0.host=one.example.com", "--upstreams.0.port=80", "--",
+		"a.txt", "b.txt",
+	}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expect args %q, got %q", expectedArgs, args)
+	}
+	loaded := &DumpConfig{}
+	if err := New(WithArgs(args...), WithEnvMap(nil)).Process(loaded); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	cfg.Password, cfg.Upstreams[0].Timeout = RedactedValue, 5*time.Second
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("Expect dumped args to reproduce configuration %+v, got %+v", *cfg, *loaded)
+	}
+
+	t.Log("Expect env file to reproduce configuration")
+
+	data, err := Dump(cfg, FormatEnv)
+	if err != nil {
+		t.Fatalf("Error occured during dump %s", err.Error())
+	}
+	if !strings.Contains(string(data), "MOTD=\"say \\\"hi\\\" # twice\"\n") || !strings.Contains(string(data), "UPSTREAMS_0_TIMEOUT=5s\n") {
+		t.Errorf("Unexpected env dump:\n%s", data)
+	}
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	loaded = &DumpConfig{}
+	if err := New(WithArgs(), WithEnvMap(nil), WithEnvFile(path)).Process(loaded); err != nil {
+		t.Fatalf("Error occured during parsing %s", err.Error())
+	}
+	loaded.Files = cfg.Files
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("Expect dumped env to reproduce configuration %+v, got %+v", *cfg, *loaded)
+	}
+
+	t.Log("Expect JSON and YAML to be structured by file keys and readable by File source")
+
+	data, err = Dump(cfg, FormatJSON)
+	if err != nil {
+		t.Fatalf("Error occured during dump %s", err.Error())
+	}
+	if !strings.Contains(string(data), `"Upstreams": [`) || !strings.Contains(string(data), `"Verbose": true`) {
+		t.Errorf("Unexpected JSON dump:\n%s", data)
+	}
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		data, err := Dump(cfg, format)
+		if err != nil {
+			t.Fatalf("Error occured during dump %s", err.Error())
+		}
+		path := filepath.Join(t.TempDir(), "config."+string(format))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		loaded := &DumpConfig{}
+		if err := New(WithSources(File(path)), WithArgs()).Process(loaded); err != nil {
+			t.Fatalf("Error occured during parsing %s", err.Error())
+		}
+		if !reflect.DeepEqual(loaded, cfg) {
+			t.Errorf("Expect dumped %s to reproduce configuration %+v, got %+v", format, *cfg, *loaded)
+		}
+	}
+
+	if _, err := Dump(cfg, "xml"); err == nil {
+		t.Error("Expect error for unsupported format")
+	}
+}
@@ -0,0 +1,52 @@
+package configrant
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(structs.Converters)
+)
+
+// RegisterConverter registers function which converts raw string value into T for all loaders.
+// Converter is consulted before any built-in conversion, including slice elements and map keys
+// and values of type T, so it can teach configrant types which can't implement Decoder
+func RegisterConverter[T any](convert func(value string) (T, error)) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[typeOf[T]()] = converterOf(convert)
+}
+
+// WithConverter registers converter into T for this Loader only, it takes precedence over converter registered by RegisterConverter
+func WithConverter[T any](convert func(value string) (T, error)) Option {
+	return func(l *Loader) {
+		l.converters[typeOf[T]()] = converterOf(convert)
+	}
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func converterOf[T any](convert func(value string) (T, error)) structs.Converter {
+	return func(value string) (interface{}, error) {
+		return convert(value)
+	}
+}
+
+func (l *Loader) registeredConverters() structs.Converters {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	registered := make(structs.Converters, len(converters)+len(l.converters))
+	for typ, converter := range converters {
+		registered[typ] = converter
+	}
+	for typ, converter := range l.converters {
+		registered[typ] = converter
+	}
+	return registered
+}
@@ -12,9 +12,26 @@ Configrant uses special `cfgrant` tag to provide details on field maintenance.
 
 Following options are supported:
 
-	arg     - command line argument
-	env     - environment variable name
-	default - default value
+	arg        - command line argument
+	short      - short alias for command line argument
+	env        - environment variable name
+	key        - configuration file key (field name is used if not specified)
+	prefix     - name segment of the nested struct used for derived names (see Derived names)
+	default    - default value
+	layout     - time.Time layout, RFC3339 is used by default
+	sep        - separator of slice, array or map elements, replaces the outermost delimiter
+	kvsep      - separator of map key and value, ':' is used by default
+	desc       - field description printed in usage
+	file       - file the value is read from if it exists (see Value files)
+	required   - field must get value from one of the sources, option has no value
+	positional - field collects positional command line arguments, option has no value
+	expand     - references in field value are expanded, option has no value (see Expansion)
+	secret     - field value is redacted in errors, usage and reports, option has no value
+	min        - minimal value, duration or length (see Validation)
+	max        - maximal value, duration or length (see Validation)
+	oneof      - allowed values separated by '|'
+	pattern    - regular expression value must match
+	validate   - names of custom validators separated by '|'
 
 For struct example mentioned above, we tell configrant:
 
@@ -22,7 +39,13 @@ For struct example mentioned above, we tell configrant:
 
 2. Take default value for field isAsync.
 
-If on structure initialization non-zero value has been provided for field, it won't be overwritten by configrant, even if corresponding tag is specified. So, if value is provided, field stays unchanged; if not - command line argument has highest priority, following environment variable and default value in the end.
+Command line argument has highest priority, following environment variable, configuration file, value provided on structure initialization and default value in the end.
+So, if non-zero value has been provided for field on structure initialization, it overrides default value, but it is overwritten by argument, environment variable or configuration file.
+The order can be changed with WithSources option (see Sources), where Literal stands for the value provided on initialization.
+If you want fields with non-zero value to stay unchanged regardless of sources, use WithPreserveNonZero option:
+
+	cfg := &Config{Retries: 5}
+	err := configrant.New(configrant.WithPreserveNonZero()).Process(cfg) // Retries stays 5 even if RETRIES_ENV is set
 
 Simple example
 
@@ -71,15 +94,96 @@ For tag options, please, use comma as a separator. Using different separator mig
 		Retries int    `cfgrant:"env:ENV_RETRIES,option:value"`              // correct tag, but property 'option' is ignored
 	}
 
-All basic Go types are supported. There is also support for time.Duration, as this type is used pretty frequently for configuration (timeout, etc.). Please, see the whole list:
+Option value containing comma must be enclosed in single quotes, quoted value is taken as is, including leading and trailing spaces.
+Alternatively comma can be escaped with backslash (doubled, since struct tag is a Go string):
+
+	type Config struct {
+		Greeting string   `cfgrant:"default:'Hello, world'"`
+		Farewell string   `cfgrant:"default:Bye\\, world"`
+		Hosts    []string `cfgrant:"sep:',',default:'a,b,c'"`
+	}
+
+Malformed tag (quote is not closed, option which requires value has none, 'required' or 'positional' has value, etc.)
+is reported by Process as FieldError which matches ErrInvalidTag.
+
+Unknown or duplicated options and options with empty names don't prevent processing. Use Check to find them together with
+unsupported field types and environment variables, command line arguments or configuration file keys used by several fields, e.g. in a test:
+
+	func TestConfig(t *testing.T) {
+		if err := configrant.Check(&Config{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+Check doesn't consult any source and leaves passed configuration untouched. Loader created with WithStrictTags option
+runs Check on each Process and fails if any problem is found.
+
+The same problems can be found at lint stage with analyzer from package github.com/umalmyha/configrant/analysis/cfgranttag,
+which also reports default values that can't be converted into field type and tags on unexported fields. Analyzer lives
+in separate module github.com/umalmyha/configrant/analysis, so golang.org/x/tools is not required by configrant itself.
+The module builds against configrant from the same checkout:
+
+	cd analysis && go install ./cfgranttag/cmd/cfgranttag
+	go vet -vettool=$(which cfgranttag) ./...
+
+All basic Go types are supported. There is also support for standard library types which are used pretty frequently for configuration (timeout, endpoint, etc.). Please, see the whole list:
 
 	- string
 	- bool
 	- int
 	- float
 	- slice
+	- array
 	- map
-	- time.Duration
+	- time.Duration (5s, 1m30s)
+	- time.Time     (RFC3339 by default, use 'layout' option to change format)
+	- url.URL       (absolute URL, scheme://host/path)
+	- net.IP        (192.168.0.1, 2001:db8::1)
+	- net.IPNet     (CIDR notation, 192.168.0.0/24)
+	- regexp.Regexp (RE2 syntax)
+	- os.FileMode   (octal number, 0644)
+
+	type Config struct {
+		Since   time.Time      `cfgrant:"default:01.01.2022,layout:02.01.2006"`
+		Allowed []net.IPNet    `cfgrant:"default:10.0.0.0/8;192.168.0.0/16"`
+		Filter  *regexp.Regexp `cfgrant:"default:^api/v[0-9]+$"`
+	}
+
+Any other type can be used if it (or pointer to it) implements Decoder, encoding.TextUnmarshaler or encoding.BinaryUnmarshaler,
+so net.IP, big.Int, time.Time, url.URL or your own enums work out of the box. Struct of such type is considered as a single field:
+
+	type Level int
+
+	func (l *Level) Decode(value string) error {
+		switch value {
+		case "debug":
+			*l = 0
+		case "info":
+			*l = 1
+		default:
+			return fmt.Errorf("unknown level %s", value)
+		}
+		return nil
+	}
+
+	type Config struct {
+		Level  Level    `cfgrant:"default:info"`
+		IP     net.IP   `cfgrant:"default:127.0.0.1"`
+		Amount *big.Int `cfgrant:"default:100000000000000000000"`
+	}
+
+Types which you can't add methods to are supported with converters. Converter registered with RegisterConverter is used by all loaders,
+converter passed with WithConverter option is used by single Loader only. Converters take precedence over built-in conversions,
+and are applied to slice elements, map keys and values as well:
+
+	configrant.RegisterConverter(func(value string) (decimal.Decimal, error) {
+		return decimal.NewFromString(value)
+	})
+
+	type Config struct {
+		Price  decimal.Decimal            `cfgrant:"default:9.99"`
+		Prices map[string]decimal.Decimal `cfgrant:"default:basic:9.99;pro:19.99"`
+	}
 
 Slice elements must be separated by semicolon:
 
@@ -95,6 +199,30 @@ Map elements must be separated by semicolon and each key-value pair must be defa
 		M2 map[string]int `cfgrant:"default:a-1;b-2;c-3"` // map elements are defined incorrectly -> key-value format is incorrect
 	}
 
+Arrays are written the same way as slices, but the number of elements must match array length:
+
+	type Config struct {
+		Origin [3]float64 `cfgrant:"default:0;0;1"` // exactly 3 elements are required
+	}
+
+Collections can be nested. Each nesting level uses its own delimiter, from the outermost level to the innermost one: semicolon, '|' and '~'.
+The hierarchy can be replaced with WithDelimiters option, the outermost delimiter of a single field is replaced with 'sep' option:
+
+	type Config struct {
+		Matrix [][]int             `cfgrant:"default:1|2;3|4"`         // [[1 2] [3 4]]
+		Labels []map[string]string `cfgrant:"default:env:prod|tier:web"` // [map[env:prod tier:web]]
+		Ports  map[string][]int    `cfgrant:"default:http:80|8080"`      // map[http:[80 8080]]
+	}
+
+Map key is separated from value by the first ':' (or 'kvsep' option), so values may contain it. Delimiter preceded by backslash
+is a part of the element. Positional arguments and configuration file lists are escaped this way automatically:
+
+	type Config struct {
+		Endpoints map[string]string `cfgrant:"default:api:http://localhost:8080;web:https://example.com"`
+		Headers   map[string]string `cfgrant:"kvsep:=,default:Accept=application/json"`
+		Queries   []string          `cfgrant:"default:a=1\\;b=2;c=3"` // [a=1;b=2 c=3]
+	}
+
 Embedded strucutres are supported as well. Tag is not required for them:
 
 	type SubConfig struct {
@@ -113,18 +241,360 @@ You can specify fields for ignoring explicitly by adding tag `cfgrant:"-"`. If c
 		Retries int	              // not tagged, has no effect -> if on initialization we set Retries equal to 3 it won't be overwritten
 	}
 
+Fields which must be set are marked with 'required' option. If no source supplies value for such field (and it isn't initialized), Process fails with ErrRequired:
+
+	type Config struct {
+		Password string `cfgrant:"env:DB_PASSWORD,required"`
+	}
+
 You can use pointers as well:
 
 	type Config struct {
 		Name *string `cfgrant:"default:James"`
 	}
 
-When passing your command line arguments, follow the format arg=value. Value can be omitted for boolean arguments:
+Command line arguments are parsed in POSIX/GNU style. Name in 'arg' option may be long (--timeout) or short (-t), name without leading dash is considered long.
+Short alias for long name is defined with 'short' option, name without leading dash is considered short there (short:v is -v). Slice field tagged with 'positional' option collects arguments which don't belong to any flag:
+
+	type Config struct {
+		Timeout time.Duration `cfgrant:"arg:--timeout,short:-t"`
+		Verbose bool          `cfgrant:"arg:--verbose,short:-v"`
+		Cache   bool          `cfgrant:"arg:--cache,default:true"`
+		Files   []string      `cfgrant:"positional"`
+	}
+
+	go run main.go --timeout 5s -v --no-cache a.txt -- b.txt --c.txt
+
+Following forms are supported:
 
-	go run main.go timeout=5s inBackground
+	--name=value, --name value   - long flag
+	-n=value, -n value, -nvalue  - short flag
+	-abc                         - combined short boolean flags
+	--flag, --no-flag            - boolean flag and its negation, value can be omitted for boolean flags
+	--                           - terminator, all following arguments are positional
+
+Arguments which don't belong to any field are ignored, unless Loader is created with WithStrictArgs option, in which case Process fails with ErrUnknownArg.
+Non-boolean argument which is the last one and has no value makes Process fail with ErrMissingValue.
 
 For command line arguments slices and maps are possible as well: elements enumeration follows the same rules as for 'default' option.
 
+Derived names
+
+Instead of writing env option for each field, environment variable names can be derived from Go field path with WithAutoEnv option,
+global prefix is added to each name. Command line argument names are derived with WithAutoArgs option in the same way.
+Explicit env and arg options still take precedence. Nested struct segment can be replaced with prefix option:
+
+	type DatabaseConfig struct {
+		Host string
+		Port int    `cfgrant:"default:5432"`
+		User string `cfgrant:"env:PGUSER"`
+	}
+
+	type Config struct {
+		LogLevel string
+		Database DatabaseConfig `cfgrant:"prefix:db"`
+	}
+
+	loader := configrant.New(configrant.WithAutoEnv("APP"), configrant.WithAutoArgs())
+
+	// LogLevel      -> APP_LOG_LEVEL,   --log-level
+	// Database.Host -> APP_DB_HOST,     --db-host
+	// Database.Port -> APP_DB_PORT,     --db-port
+	// Database.User -> PGUSER,          --db-user
+
+Environment variables are derived in SCREAMING_SNAKE case and arguments in kebab case by default, use WithEnvCase and WithArgCase to change it.
+
+Configuration file
+
+Values can be taken from a configuration file as well. Path to the file is taken from command line argument --config or, if it is not passed, from environment variable CONFIG_FILE.
+JSON (.json), YAML (.yaml, .yml) and TOML (.toml) formats are supported. Each field is looked up by its name (case-insensitive) or by 'key' option,
+embedded structures are looked up in nested sections:
+
+	type SubConfig struct {
+		Name string
+	}
+
+	type Config struct {
+		Retries  int            `cfgrant:"env:RETRIES_ENV,default:3"`
+		Timeout  time.Duration  `cfgrant:"key:request_timeout"`
+		Sequence map[string]int
+		Sc       SubConfig      `cfgrant:"key:sub"`
+	}
+
+	// config.yaml
+	retries: 5
+	request_timeout: 10s
+	sequence:
+	  first: 1
+	  second: 2
+	sub:
+	  name: configrant
+
+	go run main.go --config=config.yaml
+
+File values are converted the same way as 'default' option, so durations are defined as strings (10s) and lists or mappings are used for slices and maps.
+
+Slices and maps of structs
+
+Fields of type []T, []*T or map[K]T, where T is a struct, are maintained element by element, so each element gets its own tags and defaults applied.
+Elements are discovered by index or key: env and arg options of element fields are relative to the collection ones, file sections are used as they are:
+
+	type Upstream struct {
+		Host string `cfgrant:"env:HOST,arg:host,required"`
+		Port int    `cfgrant:"env:PORT,arg:port,default:80"`
+	}
+
+	type Database struct {
+		DSN string `cfgrant:"env:DSN,key:dsn"`
+	}
+
+	type Config struct {
+		Upstreams []Upstream          `cfgrant:"env:UPSTREAMS,arg:upstreams,key:upstreams"`
+		Databases map[string]Database `cfgrant:"env:DATABASES,key:databases"`
+	}
+
+	UPSTREAMS_0_HOST=a.example.com DATABASES_PRIMARY_DSN=postgres://primary go run main.go --upstreams.1.host=b.example.com
+
+	upstreams:
+	  - host: a.example.com
+	  - host: b.example.com
+	    port: 8080
+	databases:
+	  primary:
+	    dsn: postgres://primary
+
+Elements found in any source are merged with elements which exist before processing, so existing elements are kept
+and slice grows up to the highest index plus one. Indices must not leave gaps
+(elements which exist before processing fill them), otherwise the field is reported in Errors. Map keys taken from environment variables are lower-cased
+and are told apart from element field names by known element variables, so DATABASES_READ_REPLICA_DSN belongs to read_replica element.
+Variables which don't belong to any element field are skipped, variables which can be split in several ways are reported in Errors. Boolean element command line arguments take no value,
+like other boolean flags, e.g. --upstreams.0.tls. If no source has elements, the collection stays unchanged.
+
+Sources
+
+Process consults command line arguments, environment variables, configuration file, value provided on initialization and default values in this order. Each of them is a Source,
+so the order can be changed and own sources can be added by creating a Loader:
+
+	type secretsSource struct {
+		secrets map[string]string
+	}
+
+	func (s *secretsSource) Name() string {
+		return "secrets"
+	}
+
+	func (s *secretsSource) Lookup(field *configrant.FieldInfo) (string, bool, error) {
+		value, ok := s.secrets[field.EnvVarName]
+		return value, ok, nil
+	}
+
+	loader := configrant.New(configrant.WithSources(
+		configrant.Args(),
+		&secretsSource{secrets: secrets},
+		configrant.Env(),
+		configrant.File("config.yaml"),
+		configrant.Literal(),
+		configrant.Defaults(),
+	))
+	err := loader.Process(cfg)
+
+FieldInfo describes the field which value is looked up: Go field path, arg, env, key and default options and field type.
+The first source which finds a value wins. If none of the sources finds a value, field stays unchanged.
+Sources which must load their data before lookups (e.g. read a file) can implement Binder, which returns a copy of the source loaded
+for a single Process call, or Preparer, which loads the data into the source itself and so makes Loader unsafe for concurrent use.
+Sources which can discover elements of slices and maps of structs implement Enumerator.
+
+Loader has no global state: command line arguments are parsed and configuration files are loaded on each Process call,
+so the same Loader can be used by several goroutines. Arguments and environment can be supplied explicitly,
+which is handy for tests and for processing several configurations in one program:
+
+	loader := configrant.New(
+		configrant.WithArgs("--timeout=5s", "--config", "config.yaml"), // os.Args[1:] by default
+		configrant.WithLookupEnv(func(name string) (string, bool) {     // os.LookupEnv by default
+			value, ok := env[name]
+			return value, ok
+		}),
+	)
+
+Environment can be supplied as a map (WithEnvMap), as a slice in os.Environ format, e.g. environment of a child process (WithEnviron),
+or as a lookup function (WithLookupEnv). Variables from .env file are added with WithEnvFile, variables already present in environment win:
+
+	loader := configrant.New(configrant.WithEnvFile(".env"))
+
+	# .env
+	export DB_HOST=localhost
+	DB_PASSWORD="secret" # comment
+
+Hot reload
+
+Watch processes configuration and keeps it up to date: configuration is reloaded when configuration file changes or SIGHUP is received.
+Each reload populates a fresh copy of the struct and swaps it in atomically, so snapshot is never modified once it is returned by Config:
+
+	w, err := configrant.Watch(&Config{})
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer w.Close()
+
+	w.OnChange(func(cfg interface{}, changes []configrant.Change) {
+		for _, change := range changes {
+			fmt.Printf("%s changed from %v to %v\n", change.Path, change.Old, change.New)
+		}
+	})
+
+	cfg := w.Config().(*Config) // current snapshot
+
+Values which struct has before Watch is called are used as a template for each reload. If reload fails, the current snapshot is kept and error is passed to OnError callbacks.
+Fields of slices and maps of structs elements are compared one by one, so change of element field is reported with element path, e.g. Upstreams[0].Host.
+Old and new values of secret fields and of fields which reference them are reported as RedactedValue, read them from snapshots if needed.
+
+Validation
+
+Values are validated after all fields are maintained. Fields which failed to be maintained are not validated, neither is Validate() method
+of structs which contain them, the rest of fields are validated and their violations are reported along with maintaining errors.
+Following rules are supported:
+
+	min, max - bounds for numbers and durations, bounds for length of strings, slices and maps
+	oneof    - allowed values separated by '|', each element is checked for slices
+	pattern  - regular expression strings must match, each element is checked for slices
+	validate - names of validators registered with RegisterValidator or WithValidator, separated by '|'
+
+Configuration struct and its substructures can implement Validate() error method, which is called after field rules are checked:
+
+	type Config struct {
+		Retries int           `cfgrant:"default:3,min:1,max:5"`
+		Level   string        `cfgrant:"env:LEVEL,default:info,oneof:debug|info|warn"`
+		Timeout time.Duration `cfgrant:"default:5s,min:1s,max:1m"`
+		Port    int           `cfgrant:"default:8080,validate:port"`
+	}
+
+	func (c *Config) Validate() error {
+		if c.Level == "debug" && c.Retries > 1 {
+			return errors.New("retries must be disabled in debug mode")
+		}
+		return nil
+	}
+
+	configrant.RegisterValidator("port", func(value interface{}) error {
+		if port := value.(int); port < 1 || port > 65535 {
+			return errors.New("port is out of range")
+		}
+		return nil
+	})
+
+All violations are reported in Errors, each of them is ValidationError which matches ErrInvalid.
+
+Usage
+
+Usage prints table with command line argument, environment variable, type, default value and description of each field, UsageMarkdown prints the same table in Markdown format:
+
+	type Config struct {
+		Url     string        `cfgrant:"arg:--url,env:URL_ENV,default:http://localhost:3000,desc:API endpoint"`
+		Timeout time.Duration `cfgrant:"arg:--timeout,default:5s"`
+	}
+
+	configrant.Usage(&Config{}, os.Stdout)
+
+	FIELD    ARG        ENV      TYPE           DEFAULT                DESCRIPTION
+	Url      --url      URL_ENV  string         http://localhost:3000  API endpoint
+	Timeout  --timeout  -        time.Duration  5s                     -
+
+If -h or --help command line argument is passed and no field claims it, Process prints usage to os.Stderr (see WithUsageOutput) and returns ErrHelp without maintaining configuration.
+
+Dump
+
+Dump writes populated configuration back in FormatEnv (NAME=value lines), FormatArgs, FormatJSON or FormatYAML using the same names
+and encoding fields are parsed with, e.g. for debugging. DumpArgs and DumpEnviron return command line arguments and environment
+variables, e.g. to launch child process with the same settings:
+
+	args, err := configrant.DumpArgs(cfg) // [--timeout=7s --tags=a;b;c]
+	data, err := configrant.Dump(cfg, configrant.FormatYAML)
+
+JSON and YAML are structured by configuration file keys, so they are readable by File source. Values of secret fields are redacted.
+
+Errors
+
+Process doesn't stop on the first field which can't be maintained. Instead every failure is collected and returned as Errors, where each FieldError describes
+Go field path, source of the raw value (arg, env or default), the raw value and the underlying error:
+
+	type Config struct {
+		Retries int           `cfgrant:"env:RETRIES_ENV,default:3"`
+		Timeout time.Duration `cfgrant:"default:5 seconds"`
+	}
+
+	cfg := &Config{}
+	err := configrant.Process(cfg) // RETRIES_ENV=three
+	var errs configrant.Errors
+	if errors.As(err, &errs) {
+		for _, fieldErr := range errs {
+			fmt.Println(fieldErr.Path, fieldErr.Source, fieldErr.Value, fieldErr.Err)
+		}
+	}
+
+Errors supports errors.Is and errors.As, so underlying errors (strconv.ErrSyntax, *strconv.NumError, etc.) are reachable as well.
+
+Expansion
+
+References ${NAME} and ${NAME:-fallback} are expanded in values of fields tagged with 'expand' option or in values of all fields
+if Loader is created with WithExpansion option. NAME is path of another configuration field or environment variable, fallback is
+used if referenced value is empty. Referenced fields are resolved from the same sources regardless of fields order, references
+which form a cycle are reported with ErrInvalidExpansion. Value which references secret field is redacted in reports, dumps
+and reload changes as well. $$ stands for literal $:
+
+	type Config struct {
+		Server struct {
+			Host string `cfgrant:"default:localhost"`
+			Port int    `cfgrant:"default:3000"`
+		}
+		URL   string `cfgrant:"expand,default:http://${Server.Host}:${Server.Port}/${API_PREFIX:-api}"`
+		Price string `cfgrant:"expand,default:$$5"`
+	}
+
+Value files
+
+Docker and Kubernetes mount secrets as files. If environment variable of the field isn't set, Env source reads the value from file pointed by
+the variable with _FILE suffix. File given by 'file' option is read by the field itself, if such file exists, right before Defaults source
+or after all sources if Defaults isn't used, and is reported as value-file source:
+
+	type Config struct {
+		Password string `cfgrant:"env:DB_PASSWORD,file:/run/secrets/db_password"`
+	}
+
+	DB_PASSWORD_FILE=/run/secrets/db ./app
+
+Single trailing newline is trimmed. Files larger than MaxValueFileSize, directories and files which can't be read are reported in Errors.
+
+Secrets
+
+Values of fields tagged with 'secret' option are replaced with RedactedValue wherever configrant renders them: FieldError value and message,
+default value printed in usage and provenance report. Secret wrapper keeps the value hidden outside of configrant as well, it is printed
+by fmt and encoded to JSON as RedactedValue, use Value method to get the wrapped value:
+
+	type Config struct {
+		Password string                   `cfgrant:"env:DB_PASSWORD,secret"`
+		Token    configrant.Secret[string] `cfgrant:"env:API_TOKEN"`
+	}
+
+	fmt.Printf("%+v", cfg) // {Password:hunter2 Token:******}
+	client.Authorize(cfg.Token.Value())
+
+Field of Secret type is maintained like field of the wrapped type and is secret even without 'secret' option. Option on substructure
+or slice or map of structs makes all their fields secret.
+
+Provenance report
+
+ProcessWithReport maintains configuration like Process and reports which source supplied the final value of each field, the raw value,
+environment variable, command line arguments and configuration file key consulted and values of lower-priority sources which have been shadowed:
+
+	report, err := configrant.ProcessWithReport(cfg) // RETRIES_ENV=5
+	fmt.Print(report)
+
+	FIELD    SOURCE   VALUE  ENV          ARG  SHADOWED
+	Retries  env      "5"    RETRIES_ENV  -    default="3"
+	Timeout  default  "5s"   -            -    -
+
+Report is encoded to JSON as well, use Report.Lookup to get provenance of the particular field by its path.
+
 Compex example
 
 Please, see below some complex example with different field types and embedded structure:
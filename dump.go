@@ -0,0 +1,235 @@
+package configrant
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/umalmyha/configrant/internal/cfgargs"
+	"github.com/umalmyha/configrant/internal/cfgfile"
+	"github.com/umalmyha/configrant/internal/structs"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a format configuration is dumped in
+type Format string
+
+const (
+	// FormatEnv writes NAME=value lines of fields which have environment variable, readable by WithEnvFile
+	FormatEnv Format = "env"
+	// FormatArgs writes command line arguments one per line, see DumpArgs
+	FormatArgs Format = "args"
+	// FormatJSON writes JSON object structured by configuration file keys, readable by File source
+	FormatJSON Format = "json"
+	// FormatYAML writes YAML document structured by configuration file keys, readable by File source
+	FormatYAML Format = "yaml"
+)
+
+// Dump writes configuration in given format using default Loader, see Loader.Dump
+func Dump(cfg interface{}, format Format) ([]byte, error) {
+	return New().Dump(cfg, format)
+}
+
+// DumpArgs returns command line arguments which reproduce configuration using default Loader, see Loader.DumpArgs
+func DumpArgs(cfg interface{}) ([]string, error) {
+	return New().DumpArgs(cfg)
+}
+
+// DumpEnviron returns environment variables which reproduce configuration using default Loader, see Loader.DumpEnviron
+func DumpEnviron(cfg interface{}) ([]string, error) {
+	return New().DumpEnviron(cfg)
+}
+
+// Dump writes configuration in given format using the same names and encoding fields are parsed with,
+// so slices are written as a;b;c and durations as 7s. Values of secret fields are redacted, so they must
+// be passed separately. Fields with empty value are omitted from env and args formats
+func (l *Loader) Dump(cfg interface{}, format Format) ([]byte, error) {
+	values, err := l.values(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatEnv:
+		var buf bytes.Buffer
+		for _, value := range values {
+			if value.Field.EnvVarName != "" && value.Encoded != "" {
+				fmt.Fprintf(&buf, "%s=%s\n", value.Field.EnvVarName, cfgfile.QuoteEnv(value.Encoded))
+			}
+		}
+		return buf.Bytes(), nil
+	case FormatArgs:
+		args, err := dumpArgs(values)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, arg := range args {
+			fmt.Fprintln(&buf, arg)
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(dumpTree(values), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(dumpTree(values))
+	default:
+		return nil, fmt.Errorf("configrant: unsupported dump format %q", format)
+	}
+}
+
+// DumpArgs returns command line arguments in --name=value form which reproduce configuration, e.g. for child process.
+// Values of positional field follow -- terminator. Values of secret fields are redacted
+func (l *Loader) DumpArgs(cfg interface{}) ([]string, error) {
+	values, err := l.values(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return dumpArgs(values)
+}
+
+// DumpEnviron returns environment variables in os.Environ format which reproduce configuration, e.g. for child process.
+// Values of secret fields are redacted
+func (l *Loader) DumpEnviron(cfg interface{}) ([]string, error) {
+	values, err := l.values(cfg)
+	if err != nil {
+		return nil, err
+	}
+	environ := make([]string, 0, len(values))
+	for _, value := range values {
+		if value.Field.EnvVarName != "" && value.Encoded != "" {
+			environ = append(environ, value.Field.EnvVarName+"="+value.Encoded)
+		}
+	}
+	return environ, nil
+}
+
+func (l *Loader) values(cfg interface{}) ([]structs.Value, error) {
+	valueOf := reflect.ValueOf(cfg)
+	if valueOf.Kind() != reflect.Ptr || valueOf.IsNil() {
+		return nil, ErrNotPtrStruct
+	}
+	// values are encoded from a copy, since nil pointers are allocated on parsing
+	parser, err := l.parser(structs.Clone(valueOf.Elem()).Addr().Interface())
+	if err != nil {
+		return nil, err
+	}
+	return parser.Values()
+}
+
+func dumpArgs(values []structs.Value) ([]string, error) {
+	args := make([]string, 0, len(values))
+	positional := make([]string, 0)
+	for _, value := range values {
+		field := value.Field
+		if field.Positional {
+			elems, err := field.EncodeElems()
+			if err != nil {
+				return nil, &FieldError{Path: field.Path, Err: err}
+			}
+			positional = append(positional, elems...)
+			continue
+		}
+		name := field.ArgName
+		if name == "" {
+			name = field.ShortArgName
+		}
+		if name != "" && value.Encoded != "" {
+			args = append(args, cfgargs.Normalize(name)+"="+value.Encoded)
+		}
+	}
+	if len(positional) > 0 {
+		args = append(append(args, "--"), positional...)
+	}
+	return args, nil
+}
+
+// dumpNode is an object or a list of configuration file keys which keeps order fields are maintained in
+type dumpNode struct {
+	list   bool
+	keys   []string
+	values map[string]interface{}
+}
+
+func dumpTree(values []structs.Value) *dumpNode {
+	root := newDumpNode()
+	for _, value := range values {
+		keys := value.Field.FileKeys
+		node := root
+		for i, key := range keys[:len(keys)-1] {
+			child, ok := node.values[key].(*dumpNode)
+			if !ok {
+				child = newDumpNode()
+				node.put(key, child)
+			}
+			for _, index := range value.Indices {
+				child.list = child.list || index == i+1
+			}
+			node = child
+		}
+		var leaf interface{} = value.Encoded
+		if value.Native != nil {
+			leaf = value.Native
+		}
+		node.put(keys[len(keys)-1], leaf)
+	}
+	return root
+}
+
+func newDumpNode() *dumpNode {
+	return &dumpNode{values: make(map[string]interface{})}
+}
+
+func (n *dumpNode) put(key string, value interface{}) {
+	if _, ok := n.values[key]; !ok {
+		n.keys = append(n.keys, key)
+	}
+	n.values[key] = value
+}
+
+func (n *dumpNode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	open, closing := "{", "}"
+	if n.list {
+		open, closing = "[", "]"
+	}
+	buf.WriteString(open)
+	for i, key := range n.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		if !n.list {
+			encodedKey, _ := json.Marshal(key)
+			buf.Write(encodedKey)
+			buf.WriteString(":")
+		}
+		encoded, err := json.Marshal(n.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteString(closing)
+	return buf.Bytes(), nil
+}
+
+func (n *dumpNode) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if n.list {
+		node.Kind = yaml.SequenceNode
+	}
+	for _, key := range n.keys {
+		var value yaml.Node
+		if err := value.Encode(n.values[key]); err != nil {
+			return nil, err
+		}
+		if !n.list {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key})
+		}
+		node.Content = append(node.Content, &value)
+	}
+	return node, nil
+}
@@ -1,25 +1,253 @@
 package cfgargs
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
 
-var args map[string]string
+var (
+	ErrUnknownArg   = errors.New("unknown command line argument")
+	ErrMissingValue = errors.New("command line argument requires a value")
+)
 
-func Lookup(name string) string {
-	return args[name]
+// Spec describes flag known to parser. Prefix spec makes known every flag which
+// name starts with spec name followed by dot, e.g. --upstreams.0.host for --upstreams,
+// its BoolNames are names of boolean flags relative to spec name, * matches any element
+// key, e.g. *.tls makes --upstreams.0.tls boolean
+type Spec struct {
+	Names     []string
+	IsBool    bool
+	Prefix    bool
+	BoolNames []string
 }
 
-func Parse(arguments []string) {
-	args = make(map[string]string)
-	for _, arg := range arguments {
-		key, value := argKeyValue(arg)
-		args[key] = value
+// helpSpec describes -h and --help flags, unless they are claimed by other specs
+var helpSpec = Spec{Names: []string{"-h", "--help"}, IsBool: true}
+
+// Args holds result of parsing, nil Args has no flags and positional arguments
+type Args struct {
+	flags      map[string]string
+	positional []string
+	help       []string
+}
+
+func (a *Args) Lookup(name string) string {
+	if a == nil {
+		return ""
+	}
+	return a.flags[Normalize(name)]
+}
+
+// Names returns sorted names of all parsed flags
+func (a *Args) Names() []string {
+	if a == nil {
+		return nil
+	}
+	names := make([]string, 0, len(a.flags))
+	for name := range a.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Args) Positional() []string {
+	if a == nil {
+		return nil
+	}
+	return a.positional
+}
+
+func (a *Args) HelpRequested() bool {
+	if a == nil {
+		return false
+	}
+	for _, name := range a.help {
+		if _, ok := a.flags[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns flag name in the form it is stored after parsing: names without
+// leading dash are considered long flags, so 'timeout' and '--timeout' are the same flag
+func Normalize(name string) string {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return name
+	}
+	return "--" + name
+}
+
+// NormalizeShort is Normalize for names of short aliases: names without leading
+// dash are considered short flags, so 'v' and '-v' are the same flag
+func NormalizeShort(name string) string {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return name
+	}
+	return "-" + name
+}
+
+// Parse parses command line arguments (program name excluded) according to specs:
+// --name=value, --name value, -n value, -n=value, combined short booleans -abc, short
+// flag with attached value -n5, --no-name negation for booleans and -- terminator.
+// Arguments which don't belong to any flag are collected as positional. Unknown flags
+// are stored as well, unless strict is set, in which case error is returned for them.
+// Error is returned for known non-boolean flag which is the last argument and has no value
+func Parse(arguments []string, specs []Spec, strict bool) (*Args, error) {
+	args := &Args{flags: make(map[string]string), positional: make([]string, 0)}
+	known := knownFlags(specs)
+	for _, name := range helpSpec.Names {
+		if known[name] == &helpSpec {
+			args.help = append(args.help, name)
+		}
+	}
+	unknown, missing := make([]string, 0), make([]string, 0)
+	tokens := arguments
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token == "--":
+			args.positional = append(args.positional, tokens[i+1:]...)
+			i = len(tokens)
+		case token == "-" || !strings.HasPrefix(token, "-"):
+			args.positional = append(args.positional, token)
+		default:
+			consumed, ok, complete := args.parseFlag(token, tokens[i+1:], known)
+			if !ok {
+				unknown = append(unknown, token)
+			} else if !complete {
+				missing = append(missing, token)
+			}
+			i += consumed
+		}
+	}
+	if len(missing) > 0 {
+		return args, fmt.Errorf("configrant: %w: %s", ErrMissingValue, strings.Join(missing, ", "))
+	}
+	if strict && len(unknown) > 0 {
+		return args, fmt.Errorf("configrant: %w: %s", ErrUnknownArg, strings.Join(unknown, ", "))
+	}
+	return args, nil
+}
+
+// parseFlag stores value of the flag token and returns number of following tokens consumed as its value,
+// complete is false for known flag which requires a value but has none
+func (a *Args) parseFlag(token string, rest []string, known map[string]*Spec) (consumed int, ok bool, complete bool) {
+	name, value, hasValue := token, "", false
+	if nameValue := strings.SplitN(token, "=", 2); len(nameValue) == 2 {
+		name, value, hasValue = nameValue[0], nameValue[1], true
+	}
+
+	if isBool, isKnown := lookupSpec(known, name); isKnown {
+		switch {
+		case hasValue:
+		case isBool:
+			value = "true"
+		case len(rest) > 0:
+			value, consumed = rest[0], 1
+		default:
+			return 0, true, false
+		}
+		a.flags[name] = value
+		return consumed, true, true
+	}
+
+	if strings.HasPrefix(name, "--no-") && !hasValue {
+		negated := "--" + strings.TrimPrefix(name, "--no-")
+		if isBool, isKnown := lookupSpec(known, negated); isKnown && isBool {
+			a.flags[negated] = "false"
+			return 0, true, true
+		}
+	}
+
+	if !strings.HasPrefix(name, "--") && len(name) > 2 {
+		if consumed, ok, complete = a.parseShortFlags(token, rest, known); ok {
+			return consumed, true, complete
+		}
+	}
+
+	if !hasValue {
+		value = "true"
+	}
+	a.flags[name] = value
+	return 0, false, true
+}
+
+// parseShortFlags handles combined short flags (-abc), the last one or the first non-boolean
+// one can take a value either attached (-n5, -n=5) or as the next argument (-n 5)
+func (a *Args) parseShortFlags(token string, rest []string, known map[string]*Spec) (consumed int, ok bool, complete bool) {
+	shorts := strings.TrimPrefix(token, "-")
+	values := make(map[string]string)
+	for i, r := range shorts {
+		name := "-" + string(r)
+		spec, isKnown := known[name]
+		if !isKnown {
+			return 0, false, false
+		}
+		if spec.IsBool {
+			values[name] = "true"
+			continue
+		}
+		value := strings.TrimPrefix(shorts[i+len(string(r)):], "=")
+		if value == "" {
+			if len(rest) == 0 {
+				return 0, true, false
+			}
+			value, consumed = rest[0], 1
+		}
+		values[name] = value
+		break
+	}
+	for name, value := range values {
+		a.flags[name] = value
+	}
+	return consumed, true, true
+}
+
+// lookupSpec finds spec of the flag by its exact name or by the name of prefix spec it belongs to
+// and reports whether the flag is boolean
+func lookupSpec(known map[string]*Spec, name string) (isBool bool, ok bool) {
+	if spec, ok := known[name]; ok && !spec.Prefix {
+		return spec.IsBool, true
+	}
+	for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name[:i], ".") {
+		if spec, ok := known[name[:i]]; ok && spec.Prefix {
+			for _, boolName := range spec.BoolNames {
+				if matchElementName(boolName, name[i+1:]) {
+					return true, true
+				}
+			}
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// matchElementName reports whether dot separated name matches pattern, which * segments match any segment
+func matchElementName(pattern string, name string) bool {
+	patternSegments, nameSegments := strings.Split(pattern, "."), strings.Split(name, ".")
+	if len(patternSegments) != len(nameSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != "*" && segment != nameSegments[i] {
+			return false
+		}
 	}
+	return true
 }
 
-func argKeyValue(arg string) (key string, val string) {
-	argKeyValue := strings.SplitN(arg, "=", 2)
-	if len(argKeyValue) == 2 {
-		return argKeyValue[0], argKeyValue[1]
+func knownFlags(specs []Spec) map[string]*Spec {
+	known := map[string]*Spec{"-h": &helpSpec, "--help": &helpSpec}
+	for i := range specs {
+		for _, name := range specs[i].Names {
+			if name != "" {
+				known[Normalize(name)] = &specs[i]
+			}
+		}
 	}
-	return argKeyValue[0], "true"
+	return known
 }
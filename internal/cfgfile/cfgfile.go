@@ -0,0 +1,197 @@
+package cfgfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/umalmyha/configrant/internal/structs"
+	"gopkg.in/yaml.v3"
+)
+
+type Data map[string]interface{}
+
+// Lookup returns value located by keys as a string, lists and mappings are joined with delimiters
+// from the outermost level to the innermost one, the same way they are written in default option.
+// Delimiters met in scalar values are escaped, so they stay a part of the value
+func (d Data) Lookup(keys []string, delimiters []string, kvsep string) (string, bool) {
+	value, ok := d.lookup(keys)
+	if !ok || value == nil {
+		return "", false
+	}
+	return valueString(value, delimiters, kvsep, nil), true
+}
+
+// Keys returns indices of the list or sorted keys of the mapping located by keys
+func (d Data) Keys(keys []string) []string {
+	value, ok := d.lookup(keys)
+	if !ok {
+		return nil
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		indices := make([]string, len(v))
+		for i := range v {
+			indices[i] = strconv.Itoa(i)
+		}
+		return indices
+	case map[string]interface{}:
+		mapKeys := make([]string, 0, len(v))
+		for key := range v {
+			mapKeys = append(mapKeys, key)
+		}
+		sort.Strings(mapKeys)
+		return mapKeys
+	default:
+		return nil
+	}
+}
+
+func (d Data) lookup(keys []string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(d)
+	for _, key := range keys {
+		var ok bool
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value, ok = lookupKey(v, key)
+		case []interface{}:
+			value, ok = lookupIndex(v, key)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func Load(path string) (Data, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		// numbers are kept as written, so they are parsed by field setters without float64 precision loss
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err = decoder.Decode(&raw); err == nil && decoder.More() {
+			err = fmt.Errorf("unexpected data after top-level value")
+		}
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("config file format %s is not supported, use .json, .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if raw == nil {
+		return Data{}, nil
+	}
+	m, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config file %s must contain a mapping at the top level", path)
+	}
+	return Data(m), nil
+}
+
+func lookupKey(m map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	for k, value := range m {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func lookupIndex(s []interface{}, key string) (interface{}, bool) {
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || index >= len(s) {
+		return nil, false
+	}
+	return s[index], true
+}
+
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = normalize(elem)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = normalize(elem)
+		}
+		return m
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, elem := range v {
+			s[i] = normalize(elem)
+		}
+		return s
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = normalize(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// valueString converts value to string, escaped are delimiters of collections which enclose value
+func valueString(value interface{}, delimiters []string, kvsep string, enclosing []string) string {
+	switch v := value.(type) {
+	case string:
+		return structs.EscapeDelimiters(v, enclosing...)
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []interface{}:
+		if len(delimiters) == 0 {
+			return fmt.Sprint(v)
+		}
+		nested := append(append([]string{}, enclosing...), delimiters[0])
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = valueString(elem, delimiters[1:], kvsep, nested)
+		}
+		return strings.Join(elems, delimiters[0])
+	case map[string]interface{}:
+		if len(delimiters) == 0 {
+			return fmt.Sprint(v)
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		nested := append(append([]string{}, enclosing...), delimiters[0])
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			escapedKey := structs.EscapeDelimiters(key, append(nested, kvsep)...)
+			pairs[i] = escapedKey + kvsep + valueString(v[key], delimiters[1:], kvsep, nested)
+		}
+		return strings.Join(pairs, delimiters[0])
+	default:
+		return fmt.Sprint(v)
+	}
+}
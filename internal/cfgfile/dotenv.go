@@ -0,0 +1,89 @@
+package cfgfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadEnv reads variables from .env file: KEY=VALUE lines with optional export prefix, blank lines and
+// lines starting with # are skipped. Value in double quotes supports \n, \t, \" and \\ escapes, value
+// in single quotes is taken as is, unquoted value is trimmed and may be followed by # comment
+func LoadEnv(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		nameValue := strings.SplitN(text, "=", 2)
+		name := strings.TrimSpace(nameValue[0])
+		if len(nameValue) != 2 || name == "" {
+			return nil, fmt.Errorf("failed to parse env file %s: line %d must be in format NAME=VALUE", path, line)
+		}
+		value, err := envValue(strings.TrimSpace(nameValue[1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse env file %s: line %d: %w", path, line, err)
+		}
+		env[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return env, nil
+}
+
+func envValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; {
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(raw[i])
+				}
+			case c == '"':
+				return sb.String(), nil
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("double quote is not closed")
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("single quote is not closed")
+		}
+		return raw[1 : end+1], nil
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// QuoteEnv returns value in the form LoadEnv reads it back: value is double-quoted with escapes
+// if it has spaces around, quotes, backslashes, # or line breaks, otherwise it is kept as is
+func QuoteEnv(value string) string {
+	if value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\"'\\#\n\r\t") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
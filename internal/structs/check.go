@@ -0,0 +1,84 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/umalmyha/configrant/internal/cfgargs"
+)
+
+var (
+	ErrUnsupportedType = errors.New("field type is not supported")
+	ErrConflictingName = errors.New("name is used by several fields")
+)
+
+// Check walks configuration type without consulting any source and reports malformed tags and their problems,
+// unsupported field types, environment variables, command line arguments and configuration file keys claimed by several fields.
+// Elements of collections are checked by element type, * stands for index or key in their paths
+func (cfg Parser) Check() error {
+	c := checker{envs: make(map[string]string), args: make(map[string]string), keys: make(map[string]string)}
+	if err := c.check(cfg, nil); err != nil {
+		return err
+	}
+	return c.errs.ErrorOrNil()
+}
+
+type checker struct {
+	errs Errors
+	envs map[string]string
+	args map[string]string
+	keys map[string]string
+}
+
+func (c *checker) check(cfg Parser, parent *Field) error {
+	fields, err := cfg.collectConfigFields(parent)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		field := &fields[i]
+		if field.TagErr != nil {
+			c.report(field, field.TagErr)
+			continue
+		}
+		for _, problem := range field.TagProblems {
+			c.report(field, problem)
+		}
+		c.claim(c.envs, field, field.EnvVarName, "environment variable")
+		if !field.Positional {
+			for _, name := range []string{field.ArgName, field.ShortArgName} {
+				c.claim(c.args, field, cfgargs.Normalize(name), "command line argument")
+			}
+		}
+		c.claim(c.keys, field, strings.ToLower(strings.Join(field.FileKeys, ".")), "configuration file key")
+		if field.IsCollection() {
+			sub, element := cfg.elementParser(field, "*", reflect.New(field.Elem.Type().Elem()).Elem())
+			if err := c.check(sub, &element); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := determineFieldSetter(field.Elem.Type(), field.setterOptions()); err != nil {
+			c.report(field, fmt.Errorf("%w: %v", ErrUnsupportedType, err))
+		}
+	}
+	return nil
+}
+
+// claim registers name used by the field, conflict is reported if another field has already claimed it
+func (c *checker) claim(claimed map[string]string, field *Field, name string, kind string) {
+	if name == "" {
+		return
+	}
+	if owner, ok := claimed[name]; ok {
+		c.report(field, fmt.Errorf("%w: %s %s is already used by field %s", ErrConflictingName, kind, name, owner))
+		return
+	}
+	claimed[name] = field.Path
+}
+
+func (c *checker) report(field *Field, err error) {
+	c.errs = append(c.errs, &FieldError{Path: field.Path, Err: err})
+}
@@ -0,0 +1,255 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maintainCollection rebuilds slice or map of structs from elements discovered in sources and elements
+// which exist in the field before processing. Element fields are maintained like any other fields with
+// names scoped by element index or key, existing element is used as the base for the discovered one
+func (cfg Parser) maintainCollection(field *Field, sources []Source) (Errors, error) {
+	keys, source, err := field.collectionKeys(sources)
+	if err != nil {
+		return Errors{&FieldError{Path: field.Path, Source: source, Err: err}}, nil
+	}
+	if len(keys) == 0 {
+		if field.Required && field.Elem.IsZero() {
+			return Errors{&FieldError{Path: field.Path, Err: ErrRequired}}, nil
+		}
+		return nil, nil
+	}
+
+	var errs Errors
+	maintainElement := func(key string, elem reflect.Value) error {
+		sub, element := cfg.elementParser(field, key, elem)
+		elementErrs, err := sub.maintain(&element, sources)
+		errs = append(errs, elementErrs...)
+		return err
+	}
+
+	typ := field.Elem.Type()
+	switch typ.Kind() {
+	case reflect.Slice:
+		length, err := sliceLength(keys, field.Elem.Len())
+		if err != nil {
+			return Errors{&FieldError{Path: field.Path, Err: err}}, nil
+		}
+		slice := reflect.MakeSlice(typ, length, length)
+		for i := 0; i < length; i++ {
+			elem := slice.Index(i)
+			if i < field.Elem.Len() {
+				elem.Set(Clone(field.Elem.Index(i)))
+			}
+			if err := maintainElement(strconv.Itoa(i), elem); err != nil {
+				return nil, err
+			}
+		}
+		field.Elem.Set(slice)
+	case reflect.Map:
+		keySetter, err := determineFieldSetter(typ.Key(), field.setterOptions())
+		if err != nil {
+			return Errors{&FieldError{Path: field.Path, Err: err}}, nil
+		}
+		m := reflect.MakeMapWithSize(typ, len(keys))
+		for _, key := range keys {
+			mapKey := reflect.New(typ.Key()).Elem()
+			if err := keySetter.Apply(mapKey, key); err != nil {
+				errs = append(errs, &FieldError{Path: elementPath(field, key), Value: key, Err: fmt.Errorf("invalid map key: %w", err)})
+				continue
+			}
+			elem := reflect.New(typ.Elem()).Elem()
+			if existing := field.Elem.MapIndex(mapKey); existing.IsValid() {
+				elem.Set(Clone(existing))
+			}
+			if err := maintainElement(key, elem); err != nil {
+				return nil, err
+			}
+			m.SetMapIndex(mapKey, elem)
+		}
+		// existing elements which aren't discovered are kept and maintained as well
+		for _, mapKey := range sortedMapKeys(field.Elem) {
+			if m.MapIndex(mapKey).IsValid() {
+				continue
+			}
+			elem := reflect.New(typ.Elem()).Elem()
+			elem.Set(Clone(field.Elem.MapIndex(mapKey)))
+			if err := maintainElement(fmt.Sprint(mapKey.Interface()), elem); err != nil {
+				return nil, err
+			}
+			m.SetMapIndex(mapKey, elem)
+		}
+		field.Elem.Set(m)
+	}
+	return errs, nil
+}
+
+// sliceLength returns length of slice which holds existing elements and elements discovered by indices. Every element
+// must be either discovered or exist before processing, so indices which leave gaps are rejected instead of being allocated
+func sliceLength(keys []string, existing int) (int, error) {
+	length, added := existing, 0
+	for _, key := range keys {
+		index, _ := strconv.Atoi(key)
+		if index >= len(keys)+existing {
+			return 0, fmt.Errorf("element index %d leaves gap, elements must be numbered consecutively from 0", index)
+		}
+		if index >= length {
+			length = index + 1
+		}
+		if index >= existing {
+			added++
+		}
+	}
+	if length > existing && length-existing != added {
+		return 0, fmt.Errorf("element indices leave gap, elements must be numbered consecutively from 0")
+	}
+	return length, nil
+}
+
+// collectionKeys gathers element keys from all sources which implement Enumerator. Slice elements
+// are discovered by non-negative indices only. Nothing is discovered if collection has non-zero
+// value before processing and no source preceding Literal has elements for it
+func (f *Field) collectionKeys(sources []Source) (keys []string, source string, err error) {
+	keys = make([]string, 0)
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		if _, ok := src.(LiteralSource); ok {
+			if len(keys) == 0 && !f.Elem.IsZero() {
+				return nil, "", nil
+			}
+			continue
+		}
+		enumerator, ok := src.(Enumerator)
+		if !ok {
+			continue
+		}
+		srcKeys, err := enumerator.Keys(&f.FieldInfo)
+		if err != nil {
+			return nil, src.Name(), err
+		}
+		for _, key := range srcKeys {
+			if f.Elem.Kind() == reflect.Slice {
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 {
+					continue
+				}
+				key = strconv.Itoa(index)
+			}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, "", nil
+}
+
+// elementParser returns parser of collection element and the field standing for the element itself,
+// its path, file keys and name segments are extended with element key and its scope makes element
+// field names relative, e.g. UPSTREAMS_0_HOST and --upstreams.0.host for UPSTREAMS and --upstreams
+func (cfg Parser) elementParser(field *Field, key string, elem reflect.Value) (Parser, Field) {
+	elem = extractFieldElemOf(elem)
+	element := Field{
+		FieldInfo: FieldInfo{
+			Path:       elementPath(field, key),
+			FileKeys:   append(append([]string{}, field.FileKeys...), key),
+			Type:       elem.Type(),
+			Delimiters: field.Delimiters,
+			Secret:     field.Secret,
+		},
+		Elem:           elem,
+		NameSegments:   append(append([]string{}, field.NameSegments...), key),
+		Converters:     field.Converters,
+		IsConfigurable: true,
+		scope: &elementScope{
+			env: scopedName(field.EnvVarName, "_", strings.ToUpper(key)),
+			arg: scopedName(field.ArgName, ".", key),
+		},
+	}
+	sub := Parser{
+		ValueOf:    elem.Addr(),
+		ElemOf:     elem,
+		TypeOf:     elem.Type(),
+		Naming:     cfg.Naming,
+		Converters: cfg.Converters,
+		Delimiters: cfg.Delimiters,
+		Recorder:   cfg.Recorder,
+		Expansion:  cfg.Expansion,
+		expander:   cfg.expander,
+	}
+	return sub, element
+}
+
+// elementNames lists names of element field variables and boolean element field arguments relative to the element,
+// they are collected from zero element scoped by placeholder key. Names of nested collections variables end with underscore,
+// keys of nested elements in argument names are written as *
+func (cfg Parser) elementNames(field *Field) (envNames []string, boolArgs []string, err error) {
+	if field.EnvVarName == "" && field.ArgName == "" {
+		return nil, nil, nil
+	}
+	sub, element := cfg.elementParser(field, "*", reflect.New(field.Elem.Type().Elem()).Elem())
+	fields, err := sub.collectConfigFields(&element)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range fields {
+		f := &fields[i]
+		if name := strings.TrimPrefix(f.EnvVarName, element.scope.env+"_"); field.EnvVarName != "" && name != f.EnvVarName && name != "" {
+			if f.IsCollection() {
+				name += "_"
+			}
+			envNames = append(envNames, name)
+		}
+		name := strings.TrimPrefix(f.ArgName, element.scope.arg+".")
+		if field.ArgName == "" || name == f.ArgName || name == "" {
+			continue
+		}
+		switch {
+		case f.IsCollection():
+			for _, arg := range f.ElementBoolArgs {
+				boolArgs = append(boolArgs, name+".*."+arg)
+			}
+		case f.Type.Kind() == reflect.Bool:
+			boolArgs = append(boolArgs, name)
+		}
+	}
+	return envNames, boolArgs, nil
+}
+
+// eachCollectionElement calls fn for every element of collection field, map values are passed as addressable copies
+func eachCollectionElement(field *Field, fn func(key string, elem reflect.Value) error) error {
+	switch field.Elem.Kind() {
+	case reflect.Slice:
+		for i := 0; i < field.Elem.Len(); i++ {
+			if err := fn(strconv.Itoa(i), field.Elem.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, mapKey := range sortedMapKeys(field.Elem) {
+			elem := reflect.New(field.Elem.Type().Elem()).Elem()
+			elem.Set(field.Elem.MapIndex(mapKey))
+			if err := fn(fmt.Sprint(mapKey.Interface()), elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedMapKeys returns keys of the map sorted by their string form, so elements are visited in stable order
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	mapKeys := m.MapKeys()
+	sort.Slice(mapKeys, func(i, j int) bool {
+		return fmt.Sprint(mapKeys[i].Interface()) < fmt.Sprint(mapKeys[j].Interface())
+	})
+	return mapKeys
+}
+
+func elementPath(field *Field, key string) string {
+	return fmt.Sprintf("%s[%s]", field.Path, key)
+}
@@ -0,0 +1,281 @@
+package structs
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is a field with its value encoded in the format the value is parsed from
+type Value struct {
+	Field *Field
+	// Encoded is parsed back into the same value, secret value is redacted
+	Encoded string
+	// Native is bool or number value for encoders which keep types, nil for other and secret values
+	Native interface{}
+	// Indices are positions of FileKeys which are indices of slice elements
+	Indices []int
+}
+
+// Values encodes values of all fields including fields of slices and maps of structs elements,
+// fields are listed in the order they are maintained. Value of field which references are expanded
+// is redacted as well if it contains value of secret field, since reference could have put it there
+func (cfg Parser) Values() ([]Value, error) {
+	values, err := cfg.values(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]string, 0)
+	for _, value := range values {
+		if value.Field.Secret {
+			if secret, err := encodeValue(value.Field.Elem, value.Field.setterOptions(), nil); err == nil && secret != "" {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+	for i := range values {
+		value := &values[i]
+		if value.Field.Secret || !(cfg.Expansion.All || value.Field.Expand) {
+			continue
+		}
+		for _, secret := range secrets {
+			if strings.Contains(value.Encoded, secret) {
+				value.Field.Secret = true
+				value.Encoded, value.Native = RedactedValue, nil
+				break
+			}
+		}
+	}
+	return values, nil
+}
+
+func (cfg Parser) values(parent *Field, indices []int) ([]Value, error) {
+	fields, err := cfg.collectConfigFields(parent)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, 0, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		if field.TagErr != nil {
+			continue
+		}
+		if field.IsCollection() {
+			elemIndices := indices
+			if field.Elem.Kind() == reflect.Slice {
+				elemIndices = append(append([]int{}, indices...), len(field.FileKeys))
+			}
+			err := eachCollectionElement(field, func(key string, elem reflect.Value) error {
+				sub, element := cfg.elementParser(field, key, elem)
+				elementValues, err := sub.values(&element, elemIndices)
+				values = append(values, elementValues...)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		encoded, err := field.Encode()
+		if err != nil {
+			return nil, &FieldError{Path: field.Path, Err: err}
+		}
+		value := Value{Field: field, Encoded: encoded, Indices: indices}
+		if !field.Secret && field.isNative() {
+			value.Native = field.Elem.Interface()
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Encode returns value field holds in the format it is parsed from, so slices are joined with delimiters
+// and durations are written as 7s. Non-empty value of secret field is redacted
+func (f *Field) Encode() (string, error) {
+	encoded, err := encodeValue(f.Elem, f.setterOptions(), nil)
+	if err != nil {
+		return "", err
+	}
+	return f.Redact(encoded), nil
+}
+
+// EncodeElems encodes each element of slice or array field separately, e.g. to pass them as positional arguments
+func (f *Field) EncodeElems() ([]string, error) {
+	if f.Elem.Kind() != reflect.Slice && f.Elem.Kind() != reflect.Array {
+		encoded, err := f.Encode()
+		return []string{encoded}, err
+	}
+	elems := make([]string, f.Elem.Len())
+	for i := range elems {
+		encoded, err := encodeValue(f.Elem.Index(i), f.setterOptions().nested(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		elems[i] = f.Redact(encoded)
+	}
+	return elems, nil
+}
+
+// isNative reports whether field is bool or number which isn't decoded by custom means
+func (f *Field) isNative() bool {
+	typ := f.Elem.Type()
+	if f.Converters.IsDecodable(typ) || isTimeDurationType(typ) || typ == fileModeType {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// encodeValue is the inverse of setter determined for the value type, delimiters of enclosing collections are escaped
+func encodeValue(v reflect.Value, opts setterOptions, enclosing []string) (string, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	typ := v.Type()
+	if _, ok := opts.converters[typ]; ok {
+		return EscapeDelimiters(encodeCustom(v), enclosing...), nil
+	}
+	var encoded string
+	switch typ {
+	case timeType:
+		layout := opts.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		encoded = v.Interface().(time.Time).Format(layout)
+	case urlType:
+		u := v.Interface().(url.URL)
+		encoded = u.String()
+	case ipType:
+		if v.Len() > 0 {
+			encoded = v.Interface().(net.IP).String()
+		}
+	case ipNetType:
+		if ipNet := v.Interface().(net.IPNet); ipNet.IP != nil {
+			encoded = ipNet.String()
+		}
+	case regexpType:
+		encoded = addressable(v).Interface().(*regexp.Regexp).String()
+	case fileModeType:
+		encoded = fmt.Sprintf("%#o", v.Uint())
+	}
+	if encoded != "" || isBuiltinType(typ) {
+		return EscapeDelimiters(encoded, enclosing...), nil
+	}
+	ptrType := reflect.PtrTo(typ)
+	if ptrType.Implements(decoderType) || ptrType.Implements(textUnmarshalerType) || ptrType.Implements(binaryUnmarshalerType) {
+		return EscapeDelimiters(encodeCustom(v), enclosing...), nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if len(opts.delimiters) == 0 {
+			return "", fmt.Errorf("type %s is nested deeper than delimiters hierarchy allows, extend it with more delimiters", typ)
+		}
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		encoded = v.String()
+	case reflect.Bool:
+		encoded = strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isTimeDurationType(typ) {
+			encoded = time.Duration(v.Int()).String()
+		} else {
+			encoded = strconv.FormatInt(v.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		encoded = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		encoded = strconv.FormatFloat(v.Float(), 'g', -1, typ.Bits())
+	case reflect.Slice, reflect.Array:
+		nested := append(append([]string{}, enclosing...), opts.delimiters[0])
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elem, err := encodeValue(v.Index(i), opts.nested(), nested)
+			if err != nil {
+				return "", fmt.Errorf("element %d: %w", i, err)
+			}
+			elems[i] = elem
+		}
+		return strings.Join(elems, opts.delimiters[0]), nil
+	case reflect.Map:
+		return encodeMap(v, opts, enclosing)
+	default:
+		return "", fmt.Errorf("type %s is not supported for configuration", typ)
+	}
+	return EscapeDelimiters(encoded, enclosing...), nil
+}
+
+func encodeMap(v reflect.Value, opts setterOptions, enclosing []string) (string, error) {
+	kvsep := opts.kvsep
+	if kvsep == "" {
+		kvsep = DefaultKeyValueSeparator
+	}
+	nested := append(append([]string{}, enclosing...), opts.delimiters[0])
+	pairs := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		encodedKey, err := encodeValue(key, opts.nested(), append(nested, kvsep))
+		if err != nil {
+			return "", err
+		}
+		encodedValue, err := encodeValue(v.MapIndex(key), opts.nested(), nested)
+		if err != nil {
+			return "", err
+		}
+		pairs = append(pairs, encodedKey+kvsep+encodedValue)
+	}
+	// map order is random, so pairs are sorted to get stable output
+	sort.Strings(pairs)
+	return strings.Join(pairs, opts.delimiters[0]), nil
+}
+
+// encodeCustom encodes value of type which decodes itself or is decoded by converter, since there is no inverse
+// of Decode method or converter, value is encoded with MarshalText, MarshalBinary or String method if it has one
+func encodeCustom(v reflect.Value) string {
+	ptr := addressable(v)
+	switch value := ptr.Interface().(type) {
+	case encoding.TextMarshaler:
+		if text, err := value.MarshalText(); err == nil {
+			return string(text)
+		}
+	case encoding.BinaryMarshaler:
+		if data, err := value.MarshalBinary(); err == nil {
+			return string(data)
+		}
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// addressable returns pointer to the value or to its copy if value isn't addressable, e.g. it is a map element
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr
+}
+
+func isBuiltinType(typ reflect.Type) bool {
+	switch typ {
+	case timeType, urlType, ipType, ipNetType, regexpType, fileModeType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	Path   string
+	Source string
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	if e.Source == "" {
+		return fmt.Sprintf("field %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("field %s: %s value %q: %v", e.Path, e.Source, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	if len(messages) == 1 {
+		return "configrant: " + messages[0]
+	}
+	return fmt.Sprintf("configrant: %d fields failed:\n\t%s", len(messages), strings.Join(messages, "\n\t"))
+}
+
+func (e Errors) Is(target error) bool {
+	for _, fieldErr := range e {
+		if errors.Is(fieldErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Errors) As(target interface{}) bool {
+	for _, fieldErr := range e {
+		if errors.As(fieldErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// covers reports whether field at path or one of its parents has failed
+func (e Errors) covers(path string) bool {
+	for _, fieldErr := range e {
+		if fieldErr.Path == path || isSubpath(path, fieldErr.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// contain reports whether field at path or one of its descendants has failed
+func (e Errors) contain(path string) bool {
+	for _, fieldErr := range e {
+		if fieldErr.Path == path || isSubpath(fieldErr.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSubpath(path string, parent string) bool {
+	if parent == "" {
+		return true
+	}
+	return strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
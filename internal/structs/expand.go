@@ -0,0 +1,193 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidExpansion = errors.New("invalid expansion")
+
+// Expansion configures expansion of ${NAME} and ${NAME:-fallback} references in raw values of fields.
+// NAME is either path of another configuration field, e.g. Server.Port, or environment variable
+type Expansion struct {
+	// All enables expansion in values of all fields, otherwise only fields tagged with 'expand' option are expanded
+	All bool
+	// LookupEnv resolves references which aren't paths of configuration fields
+	LookupEnv func(name string) (string, bool)
+}
+
+// expander expands values of fields maintained by one MaintainFields call. Referenced fields are resolved
+// from the same sources independently of maintenance order, resolved values are cached
+type expander struct {
+	Expansion
+	parser    Parser
+	sources   []Source
+	fields    map[string]*Field
+	resolved  map[string]string
+	resolving []string
+	// revealing are paths of fields which expanded values contain values of secret fields
+	revealing map[string]bool
+}
+
+func newExpander(cfg Parser, sources []Source) *expander {
+	return &expander{
+		Expansion: cfg.Expansion,
+		parser:    cfg,
+		sources:   sources,
+		resolved:  make(map[string]string),
+		revealing: make(map[string]bool),
+	}
+}
+
+func (e *expander) expands(f *Field) bool {
+	return e != nil && (e.All || f.Expand)
+}
+
+// expandField expands raw value of the field, the field is tracked, so references back to it are reported as cycle.
+// Field becomes secret if its expanded value contains value of secret field
+func (e *expander) expandField(f *Field, value string) (string, error) {
+	expanded, err := e.expandTracked(f, value)
+	if e.revealing[f.Path] {
+		f.Secret = true
+	}
+	return expanded, err
+}
+
+func (e *expander) expandTracked(f *Field, value string) (string, error) {
+	if cached, ok := e.resolved[f.Path]; ok {
+		return cached, nil
+	}
+	for i, path := range e.resolving {
+		if path == f.Path {
+			cycle := append(append([]string{}, e.resolving[i:]...), f.Path)
+			return "", fmt.Errorf("%w: reference cycle %s", ErrInvalidExpansion, strings.Join(cycle, " -> "))
+		}
+	}
+	e.resolving = append(e.resolving, f.Path)
+	defer func() { e.resolving = e.resolving[:len(e.resolving)-1] }()
+	expanded, err := e.expand(value)
+	if err != nil {
+		return "", err
+	}
+	e.resolved[f.Path] = expanded
+	return expanded, nil
+}
+
+// expand replaces references in value, $$ stands for literal $
+func (e *expander) expand(value string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(value, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("%w: reference %s is not closed", ErrInvalidExpansion, value[i:])
+			}
+			resolved, err := e.reference(value[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(resolved)
+			i = end
+		default:
+			sb.WriteByte('$')
+		}
+	}
+	return sb.String(), nil
+}
+
+// reference resolves NAME or NAME:-fallback, fallback is used if NAME is unset or empty and is expanded as well
+func (e *expander) reference(ref string) (string, error) {
+	name, fallback, hasFallback := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", fmt.Errorf("%w: reference ${%s} has empty name", ErrInvalidExpansion, ref)
+	}
+	value, err := e.resolve(name)
+	if err != nil {
+		return "", err
+	}
+	if value == "" && hasFallback {
+		return e.expand(fallback)
+	}
+	return value, nil
+}
+
+func (e *expander) resolve(name string) (string, error) {
+	fields, err := e.configFields()
+	if err != nil {
+		return "", err
+	}
+	field, ok := fields[name]
+	if !ok {
+		if e.LookupEnv == nil {
+			return "", nil
+		}
+		value, _ := e.LookupEnv(name)
+		return value, nil
+	}
+	value, source, winner, err := field.lookup(e.sources)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case winner < 0:
+		value = ""
+	case source == SourceLiteral:
+		value = field.literalString()
+	case e.expands(field):
+		if value, err = e.expandTracked(field, value); err != nil {
+			return "", err
+		}
+	}
+	if field.Secret || e.revealing[field.Path] {
+		// every field which is being expanded includes this value
+		for _, path := range e.resolving {
+			e.revealing[path] = true
+		}
+	}
+	return value, nil
+}
+
+// configFields indexes fields which can be referenced by their paths
+func (e *expander) configFields() (map[string]*Field, error) {
+	if e.fields != nil {
+		return e.fields, nil
+	}
+	fields, err := e.parser.Fields()
+	if err != nil {
+		return nil, err
+	}
+	e.fields = make(map[string]*Field, len(fields))
+	for i := range fields {
+		if fields[i].TagErr == nil && !fields[i].IsCollection() {
+			e.fields[fields[i].Path] = &fields[i]
+		}
+	}
+	return e.fields, nil
+}
+
+// closingBrace returns index of brace closing reference which starts at from, nested references are skipped
+func closingBrace(value string, from int) int {
+	depth := 0
+	for i := from; i < len(value); i++ {
+		switch {
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '{':
+			depth++
+			i++
+		case value[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
@@ -1,52 +1,170 @@
 package structs
 
 import (
-	"os"
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 
 	"github.com/umalmyha/configrant/internal/cfgargs"
 )
 
+var ErrRequired = errors.New("required value is not provided")
+
+const DefaultKeyValueSeparator = ":"
+
+type FieldInfo struct {
+	Path         string
+	FileKeys     []string
+	ArgName      string
+	ShortArgName string
+	Positional   bool
+	EnvVarName   string
+	DefaultValue string
+	Description  string
+	Required     bool
+	Type         reflect.Type
+	Delimiters   []string
+	// KeyValueSeparator separates key from value in map elements
+	KeyValueSeparator string
+	// ValueFile is a file field value is read from if it exists
+	ValueFile string
+	// Secret fields have their values redacted wherever they are rendered
+	Secret bool
+	// Expand enables expansion of ${NAME} references in the raw value
+	Expand bool
+	// ElementEnvNames are environment variable names of slice or map of structs element fields relative to the element,
+	// e.g. HOST for UPSTREAMS_0_HOST. Names of nested collections end with underscore
+	ElementEnvNames []string
+	// ElementBoolArgs are command line argument names of boolean element fields relative to the element,
+	// e.g. tls for --upstreams.0.tls. Keys of nested elements are written as *
+	ElementBoolArgs []string
+}
+
 type Field struct {
+	FieldInfo
 	Elem           reflect.Value
-	ArgName        string
-	EnvVarName     string
-	DefaultValue   string
+	Rules          ValidationRules
+	NameSegments   []string
+	Converters     Converters
+	Layout         string
+	Separator      string
+	TagErr         error
+	TagProblems    []error
 	IsConfigurable bool
+	scope          *elementScope
+}
+
+// elementScope holds names of collection element, names of element fields are relative to them
+type elementScope struct {
+	env string
+	arg string
 }
 
-func (f *Field) Set() error {
-	if !f.Elem.IsZero() {
+func (f *Field) set(sources []Source, record Recorder, exp *expander) *FieldError {
+	value, source, winner, err := f.lookup(sources)
+	raw := value
+	if err == nil && winner >= 0 && source != SourceLiteral && exp.expands(f) {
+		value, err = exp.expandField(f, value)
+	}
+	if record != nil {
+		record(f.provenance(sources, winner, source, value))
+	}
+	if err != nil {
+		return &FieldError{Path: f.Path, Source: source, Value: f.Redact(raw), Err: f.redactError(err, raw)}
+	}
+	if winner < 0 {
+		if f.Required && f.Elem.IsZero() {
+			return &FieldError{Path: f.Path, Err: ErrRequired}
+		}
+		return nil
+	}
+	if source == SourceLiteral {
 		return nil
 	}
-	value := f.ValueString()
-	setter, err := determineFieldSetter(f.Elem.Type())
+	setter, err := determineFieldSetter(f.Elem.Type(), f.setterOptions())
+	if err == nil {
+		err = setter.Apply(f.Elem, value)
+	}
 	if err != nil {
-		return err
+		return &FieldError{Path: f.Path, Source: source, Value: f.Redact(value), Err: f.redactError(err, value)}
 	}
-	return setter.Apply(f.Elem, value)
+	return nil
 }
 
-func (f *Field) ValueString() string {
-	argValue := cfgargs.Lookup(f.ArgName)
-	if argValue != "" {
-		return argValue
+// lookup returns value of the first source which finds it and index of this source, index is -1 if nothing is found.
+// Value file is consulted at position of DefaultSource, index of value file after all sources is len(sources)
+func (f *Field) lookup(sources []Source) (value string, source string, winner int, err error) {
+	valueFileAt := valueFilePosition(sources)
+	for i := 0; i <= len(sources); i++ {
+		if i == valueFileAt {
+			value, found, err := f.lookupValueFile()
+			if err != nil {
+				return "", SourceValueFile, -1, err
+			}
+			if found {
+				return value, SourceValueFile, i, nil
+			}
+		}
+		if i == len(sources) {
+			break
+		}
+		src := sources[i]
+		if _, ok := src.(LiteralSource); ok {
+			if !f.Elem.IsZero() {
+				return "", SourceLiteral, i, nil
+			}
+			continue
+		}
+		value, found, err := src.Lookup(&f.FieldInfo)
+		if err != nil {
+			return "", src.Name(), -1, err
+		}
+		if found {
+			return value, src.Name(), i, nil
+		}
 	}
-	envVarValue := os.Getenv(f.EnvVarName)
-	if envVarValue != "" {
-		return envVarValue
+	return "", "", -1, nil
+}
+
+// valueFilePosition returns index of the first DefaultSource or len(sources) if there is none
+func valueFilePosition(sources []Source) int {
+	for i, src := range sources {
+		if _, ok := src.(DefaultSource); ok {
+			return i
+		}
 	}
-	return f.DefaultValue
+	return len(sources)
+}
+
+func (f *Field) setterOptions() setterOptions {
+	return setterOptions{converters: f.Converters, layout: f.Layout, delimiters: f.Delimiters, kvsep: f.KeyValueSeparator}
 }
 
 func (f *Field) IsStruct() bool {
-	return f.Elem.Kind() == reflect.Struct
+	return f.Elem.Kind() == reflect.Struct && !f.Converters.IsDecodable(f.Elem.Type())
 }
 
-func NewField(typeOfField reflect.StructField, elemOfField reflect.Value) (field Field) {
+// IsCollection reports whether field is a slice or a map of structs, which elements are maintained field by field
+func (f *Field) IsCollection() bool {
+	typ := f.Elem.Type()
+	if (typ.Kind() != reflect.Slice && typ.Kind() != reflect.Map) || f.Converters.IsDecodable(typ) {
+		return false
+	}
+	elemType := typ.Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	return elemType.Kind() == reflect.Struct && !f.Converters.IsDecodable(elemType)
+}
+
+func NewField(parent *Field, typeOfField reflect.StructField, elemOfField reflect.Value) (field Field) {
 	elemOfField = extractFieldElemOf(elemOfField)
 	field = Field{
+		FieldInfo: FieldInfo{
+			Path: typeOfField.Name,
+			Type: elemOfField.Type(),
+		},
 		Elem:           elemOfField,
 		IsConfigurable: true,
 	}
@@ -55,13 +173,66 @@ func NewField(typeOfField reflect.StructField, elemOfField reflect.Value) (field
 		field.IsConfigurable = false
 		return
 	}
-	def, env, arg := parseConfigrantTag(tagStr)
-	field.DefaultValue = def
-	field.EnvVarName = env
-	field.ArgName = arg
+	if secretElem, ok := secretElemOf(elemOfField); ok {
+		field.Elem = secretElem
+		field.Type = secretElem.Type()
+		field.Secret = true
+	}
+	opts, err := parseConfigrantTag(tagStr)
+	if err != nil {
+		field.TagErr = err
+		return
+	}
+	field.DefaultValue = opts.def
+	field.EnvVarName = opts.env
+	field.ArgName = opts.arg
+	field.ShortArgName = cfgargs.NormalizeShort(opts.short)
+	field.Positional = opts.positional
+	field.Rules = opts.rules
+	field.Layout = opts.layout
+	field.Separator = opts.sep
+	field.TagProblems = opts.problems
+	field.KeyValueSeparator = opts.kvsep
+	if field.KeyValueSeparator == "" {
+		field.KeyValueSeparator = DefaultKeyValueSeparator
+	}
+	field.Description = opts.desc
+	field.ValueFile = opts.file
+	field.Required = opts.required
+	field.Secret = field.Secret || opts.secret || (parent != nil && parent.Secret)
+	field.Expand = opts.expand
+	key := opts.key
+	if key == "" {
+		key = typeOfField.Name
+	}
+	segment := opts.prefix
+	if segment == "" {
+		segment = typeOfField.Name
+	}
+	field.FileKeys = []string{key}
+	field.NameSegments = []string{segment}
+	if parent != nil {
+		field.Path = parent.Path + "." + field.Path
+		field.FileKeys = append(append([]string{}, parent.FileKeys...), key)
+		field.NameSegments = append(append([]string{}, parent.NameSegments...), segment)
+	}
+	if parent != nil && parent.scope != nil {
+		field.scope = parent.scope
+		field.EnvVarName = scopedName(field.scope.env, "_", field.EnvVarName)
+		field.ArgName = scopedName(field.scope.arg, ".", strings.TrimLeft(field.ArgName, "-"))
+		field.ShortArgName = ""
+		field.Positional = false
+	}
 	return
 }
 
+func scopedName(scope string, sep string, name string) string {
+	if scope == "" || name == "" {
+		return ""
+	}
+	return scope + sep + name
+}
+
 func extractFieldElemOf(field reflect.Value) (elemOf reflect.Value) {
 	elemOf = field
 	for elemOf.Kind() == reflect.Ptr {
@@ -73,21 +244,108 @@ func extractFieldElemOf(field reflect.Value) (elemOf reflect.Value) {
 	return
 }
 
-func parseConfigrantTag(tagStr string) (def string, env string, arg string) {
+type tagOptions struct {
+	def        string
+	env        string
+	arg        string
+	short      string
+	key        string
+	prefix     string
+	desc       string
+	file       string
+	layout     string
+	sep        string
+	kvsep      string
+	required   bool
+	positional bool
+	secret     bool
+	expand     bool
+	rules      ValidationRules
+	// problems don't prevent field maintenance, they are reported by Parser.Check
+	problems []error
+}
+
+// flagTagOptions have no value, presence of the option turns it on
+var flagTagOptions = map[string]bool{"required": true, "positional": true, "secret": true, "expand": true}
+
+func parseConfigrantTag(tagStr string) (opts tagOptions, err error) {
 	if tagStr == "" {
 		return
 	}
-	tagOptions := strings.Split(tagStr, ",")
-	for _, tagOption := range tagOptions {
-		propValue := strings.SplitN(tagOption, ":", 2)
-		prop, value := propValue[0], strings.TrimSpace(propValue[1])
+	options, err := tokenizeTag(tagStr)
+	if err != nil {
+		return opts, err
+	}
+	seen := make(map[string]bool)
+	for _, option := range options {
+		prop, value := option.name, option.value
+		if seen[prop] {
+			opts.problems = append(opts.problems, fmt.Errorf("%w: option %s is specified more than once", ErrInvalidTag, prop))
+		}
+		seen[prop] = true
+		if flagTagOptions[prop] && option.hasValue {
+			return opts, fmt.Errorf("%w: option %s has no value, remove ':%s'", ErrInvalidTag, prop, value)
+		}
+		if !flagTagOptions[prop] && !option.hasValue {
+			return opts, fmt.Errorf("%w: option %s requires value in format %s:value", ErrInvalidTag, prop, prop)
+		}
 		switch prop {
 		case "default":
-			def = value
-		case "env":
-			env = value
-		case "arg":
-			arg = value
+			opts.def = value
+		case "env", "arg", "short", "key":
+			if value == "" {
+				opts.problems = append(opts.problems, fmt.Errorf("%w: option %s has empty name", ErrInvalidTag, prop))
+			}
+			switch prop {
+			case "env":
+				opts.env = value
+			case "arg":
+				opts.arg = value
+			case "short":
+				opts.short = value
+			case "key":
+				opts.key = value
+			}
+		case "file":
+			if value == "" {
+				opts.problems = append(opts.problems, fmt.Errorf("%w: option %s has empty path", ErrInvalidTag, prop))
+			}
+			opts.file = value
+		case "prefix":
+			opts.prefix = value
+		case "layout":
+			opts.layout = value
+		case "desc":
+			opts.desc = value
+		case "sep", "kvsep":
+			if value == "" {
+				return opts, fmt.Errorf("%w: option %s requires non-empty separator", ErrInvalidTag, prop)
+			}
+			if prop == "sep" {
+				opts.sep = value
+			} else {
+				opts.kvsep = value
+			}
+		case "required":
+			opts.required = true
+		case "positional":
+			opts.positional = true
+		case "secret":
+			opts.secret = true
+		case "expand":
+			opts.expand = true
+		case "min":
+			opts.rules.Min = value
+		case "max":
+			opts.rules.Max = value
+		case "oneof":
+			opts.rules.OneOf = strings.Split(value, "|")
+		case "pattern":
+			opts.rules.Pattern = value
+		case "validate":
+			opts.rules.Validators = strings.Split(value, "|")
+		default:
+			opts.problems = append(opts.problems, fmt.Errorf("%w: unknown option %s", ErrInvalidTag, prop))
 		}
 	}
 	return
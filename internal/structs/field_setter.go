@@ -1,8 +1,13 @@
 package structs
 
 import (
+	"encoding"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -12,7 +17,76 @@ type FieldSetter interface {
 	Apply(field reflect.Value, value string) error
 }
 
-func determineFieldSetter(typ reflect.Type) (setter FieldSetter, err error) {
+type Decoder interface {
+	Decode(value string) error
+}
+
+var (
+	decoderType           = reflect.TypeOf((*Decoder)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+type Converter func(value string) (interface{}, error)
+
+type Converters map[reflect.Type]Converter
+
+type setterOptions struct {
+	converters Converters
+	layout     string
+	delimiters []string
+	kvsep      string
+}
+
+// nested returns options for elements of collection, which are separated by the next delimiter in hierarchy
+func (o setterOptions) nested() setterOptions {
+	nested := o
+	nested.delimiters = o.delimiters[1:]
+	return nested
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	urlType      = reflect.TypeOf(url.URL{})
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+	regexpType   = reflect.TypeOf(regexp.Regexp{})
+	fileModeType = reflect.TypeOf(os.FileMode(0))
+)
+
+func determineFieldSetter(typ reflect.Type, opts setterOptions) (setter FieldSetter, err error) {
+	if converter, ok := opts.converters[typ]; ok {
+		return &converterFieldSetter{converter: converter}, nil
+	}
+	switch typ {
+	case timeType:
+		return &timeFieldSetter{layout: opts.layout}, nil
+	case urlType:
+		return new(urlFieldSetter), nil
+	case ipType:
+		return new(ipFieldSetter), nil
+	case ipNetType:
+		return new(ipNetFieldSetter), nil
+	case regexpType:
+		return new(regexpFieldSetter), nil
+	case fileModeType:
+		return new(fileModeFieldSetter), nil
+	}
+	ptrType := reflect.PtrTo(typ)
+	switch {
+	case ptrType.Implements(decoderType):
+		return new(decoderFieldSetter), nil
+	case ptrType.Implements(textUnmarshalerType):
+		return new(textUnmarshalerFieldSetter), nil
+	case ptrType.Implements(binaryUnmarshalerType):
+		return new(binaryUnmarshalerFieldSetter), nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if len(opts.delimiters) == 0 {
+			return nil, fmt.Errorf("type %s is nested deeper than delimiters hierarchy allows, extend it with more delimiters", typ)
+		}
+	}
 	switch typ.Kind() {
 	case reflect.String:
 		setter = new(stringFieldSetter)
@@ -29,15 +103,30 @@ func determineFieldSetter(typ reflect.Type) (setter FieldSetter, err error) {
 	case reflect.Float32, reflect.Float64:
 		setter = new(floatFieldSetter)
 	case reflect.Slice:
-		setter = new(sliceFieldSetter)
+		setter = &sliceFieldSetter{opts: opts}
+	case reflect.Array:
+		setter = &arrayFieldSetter{opts: opts}
 	case reflect.Map:
-		setter = new(mapFieldSetter)
+		setter = &mapFieldSetter{opts: opts}
 	default:
-		err = fmt.Errorf("type %s is not supported for configuration", typ.Name())
+		err = fmt.Errorf("type %s is not supported for configuration", typ)
 	}
 	return
 }
 
+// IsDecodable reports whether type is converted from string as a whole, so struct of such type is a single field
+func (c Converters) IsDecodable(typ reflect.Type) bool {
+	if _, ok := c[typ]; ok {
+		return true
+	}
+	switch typ {
+	case timeType, urlType, ipType, ipNetType, regexpType, fileModeType:
+		return true
+	}
+	ptrType := reflect.PtrTo(typ)
+	return ptrType.Implements(secretValueType) || ptrType.Implements(decoderType) || ptrType.Implements(textUnmarshalerType) || ptrType.Implements(binaryUnmarshalerType)
+}
+
 func isTimeDurationType(typ reflect.Type) bool {
 	return typ.Kind() == reflect.Int64 && typ.PkgPath() == "time" && typ.Name() == "Duration"
 }
@@ -93,35 +182,60 @@ func (s *floatFieldSetter) Apply(field reflect.Value, value string) error {
 	}
 }
 
-type sliceFieldSetter struct{}
+type sliceFieldSetter struct {
+	opts setterOptions
+}
 
 func (s *sliceFieldSetter) Apply(field reflect.Value, value string) error {
 	typ := field.Type()
-	values := strings.Split(strings.TrimSpace(value), ";")
+	values := splitEscaped(strings.TrimSpace(value), s.opts.delimiters[0])
 	count := len(values)
 	slice := reflect.MakeSlice(typ, count, count)
-	if err := s.fillSlice(slice, values); err != nil {
+	if err := fillElems(slice, values, s.opts.nested()); err != nil {
 		return err
 	}
 	field.Set(slice)
 	return nil
 }
 
-func (s *sliceFieldSetter) fillSlice(slice reflect.Value, values []string) error {
-	if slice.Len() > 0 {
-		setter, err := determineFieldSetter(slice.Index(0).Type())
-		if err != nil {
-			return err
-		}
-		for i, val := range values {
-			elemOf := slice.Index(i)
-			setter.Apply(elemOf, val)
+type arrayFieldSetter struct {
+	opts setterOptions
+}
+
+func (s *arrayFieldSetter) Apply(field reflect.Value, value string) error {
+	typ := field.Type()
+	values := splitEscaped(strings.TrimSpace(value), s.opts.delimiters[0])
+	if len(values) != typ.Len() {
+		return fmt.Errorf("array %s requires exactly %d elements separated by %q, got %d", typ, typ.Len(), s.opts.delimiters[0], len(values))
+	}
+	array := reflect.New(typ).Elem()
+	if err := fillElems(array, values, s.opts.nested()); err != nil {
+		return err
+	}
+	field.Set(array)
+	return nil
+}
+
+// fillElems applies values to elements of slice or array, error mentions position of the invalid element
+func fillElems(elems reflect.Value, values []string, opts setterOptions) error {
+	if elems.Len() == 0 {
+		return nil
+	}
+	setter, err := determineFieldSetter(elems.Type().Elem(), opts)
+	if err != nil {
+		return err
+	}
+	for i, val := range values {
+		if err := setter.Apply(elems.Index(i), val); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
 		}
 	}
 	return nil
 }
 
-type mapFieldSetter struct{}
+type mapFieldSetter struct {
+	opts setterOptions
+}
 
 func (s *mapFieldSetter) Apply(field reflect.Value, value string) error {
 	typ := field.Type()
@@ -130,7 +244,7 @@ func (s *mapFieldSetter) Apply(field reflect.Value, value string) error {
 		return err
 	}
 	m := reflect.MakeMap(typ)
-	keyValues := strings.Split(strings.TrimSpace(value), ";")
+	keyValues := splitEscaped(strings.TrimSpace(value), s.opts.delimiters[0])
 	for _, keyValue := range keyValues {
 		keyStr, valStr, err := s.splitKeyValuePair(keyValue)
 		if err != nil {
@@ -151,11 +265,11 @@ func (s *mapFieldSetter) Apply(field reflect.Value, value string) error {
 }
 
 func (s *mapFieldSetter) mapSetters(typ reflect.Type) (mapKeySetter FieldSetter, mapValueSetter FieldSetter, err error) {
-	mapKeySetter, err = determineFieldSetter(typ.Key())
+	mapKeySetter, err = determineFieldSetter(typ.Key(), s.opts.nested())
 	if err != nil {
 		return
 	}
-	mapValueSetter, err = determineFieldSetter(typ.Elem())
+	mapValueSetter, err = determineFieldSetter(typ.Elem(), s.opts.nested())
 	if err != nil {
 		return
 	}
@@ -163,12 +277,14 @@ func (s *mapFieldSetter) mapSetters(typ reflect.Type) (mapKeySetter FieldSetter,
 }
 
 func (s *mapFieldSetter) splitKeyValuePair(keyValue string) (key string, value string, err error) {
-	splittedPair := strings.Split(keyValue, ":")
-	if len(splittedPair) != 2 {
-		err = fmt.Errorf("invalid key-pair format is used for map, use key:value format")
-		return
+	kvsep := s.opts.kvsep
+	if kvsep == "" {
+		kvsep = DefaultKeyValueSeparator
+	}
+	key, value, found := cutEscaped(keyValue, kvsep)
+	if !found {
+		err = fmt.Errorf("invalid key-pair format %q is used for map, use key%svalue format", keyValue, kvsep)
 	}
-	key, value = splittedPair[0], splittedPair[1]
 	return
 }
 
@@ -182,3 +298,117 @@ func (s *timeDurationFieldSetter) Apply(field reflect.Value, value string) error
 	field.SetInt(int64(duration))
 	return nil
 }
+
+type decoderFieldSetter struct{}
+
+func (s *decoderFieldSetter) Apply(field reflect.Value, value string) error {
+	return field.Addr().Interface().(Decoder).Decode(value)
+}
+
+type textUnmarshalerFieldSetter struct{}
+
+func (s *textUnmarshalerFieldSetter) Apply(field reflect.Value, value string) error {
+	return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+}
+
+type binaryUnmarshalerFieldSetter struct{}
+
+func (s *binaryUnmarshalerFieldSetter) Apply(field reflect.Value, value string) error {
+	return field.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(value))
+}
+
+type converterFieldSetter struct {
+	converter Converter
+}
+
+func (s *converterFieldSetter) Apply(field reflect.Value, value string) error {
+	converted, err := s.converter(value)
+	if err != nil {
+		return err
+	}
+	convertedOf := reflect.ValueOf(converted)
+	if !convertedOf.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if !convertedOf.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("converter returned %s, but %s is expected", convertedOf.Type(), field.Type())
+	}
+	field.Set(convertedOf)
+	return nil
+}
+
+type timeFieldSetter struct {
+	layout string
+}
+
+func (s *timeFieldSetter) Apply(field reflect.Value, value string) error {
+	layout := s.layout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return fmt.Errorf("time must be in format %s: %w", layout, err)
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+type urlFieldSetter struct{}
+
+func (s *urlFieldSetter) Apply(field reflect.Value, value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("URL must be in format scheme://host/path: %w", err)
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("URL must be in format scheme://host/path: scheme is missing")
+	}
+	field.Set(reflect.ValueOf(*u))
+	return nil
+}
+
+type ipFieldSetter struct{}
+
+func (s *ipFieldSetter) Apply(field reflect.Value, value string) error {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return fmt.Errorf("IP address must be in IPv4 (192.168.0.1) or IPv6 (2001:db8::1) format")
+	}
+	field.Set(reflect.ValueOf(ip))
+	return nil
+}
+
+type ipNetFieldSetter struct{}
+
+func (s *ipNetFieldSetter) Apply(field reflect.Value, value string) error {
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return fmt.Errorf("network must be in CIDR format (192.168.0.0/24 or 2001:db8::/32): %w", err)
+	}
+	field.Set(reflect.ValueOf(*ipNet))
+	return nil
+}
+
+type regexpFieldSetter struct{}
+
+func (s *regexpFieldSetter) Apply(field reflect.Value, value string) error {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return fmt.Errorf("regular expression must be in RE2 syntax: %w", err)
+	}
+	field.Set(reflect.ValueOf(re).Elem())
+	return nil
+}
+
+type fileModeFieldSetter struct{}
+
+func (s *fileModeFieldSetter) Apply(field reflect.Value, value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return fmt.Errorf("file mode must be an octal number (0644): %w", err)
+	}
+	field.SetUint(mode)
+	return nil
+}
@@ -0,0 +1,63 @@
+package structs
+
+import (
+	"unicode"
+)
+
+type NameCase func(words []string) string
+
+type Naming struct {
+	AutoEnv   bool
+	EnvPrefix string
+	EnvCase   NameCase
+	AutoArgs  bool
+	ArgCase   NameCase
+}
+
+func (n Naming) apply(field *Field) {
+	if n.AutoEnv && field.EnvVarName == "" {
+		words := splitWords(n.EnvPrefix)
+		for _, segment := range field.NameSegments {
+			words = append(words, splitWords(segment)...)
+		}
+		field.EnvVarName = n.EnvCase(words)
+	}
+	if n.AutoArgs && field.ArgName == "" && !field.Positional {
+		words := make([]string, 0, len(field.NameSegments))
+		for _, segment := range field.NameSegments {
+			words = append(words, splitWords(segment)...)
+		}
+		field.ArgName = "--" + n.ArgCase(words)
+	}
+}
+
+// splitWords splits Go identifier (or already delimited name) into words,
+// e.g. HTTPServerPort -> HTTP Server Port, db_name -> db name
+func splitWords(name string) []string {
+	words := make([]string, 0)
+	runes := []rune(name)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
@@ -0,0 +1,82 @@
+package structs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provenance describes where the final value of the field comes from: winning source and its raw value,
+// names the field is looked up by and values of lower-priority sources which have been shadowed
+type Provenance struct {
+	Path     string        `json:"path"`
+	Source   string        `json:"source,omitempty"`
+	Value    string        `json:"value,omitempty"`
+	Env      string        `json:"env,omitempty"`
+	Args     []string      `json:"args,omitempty"`
+	Key      string        `json:"key,omitempty"`
+	Shadowed []SourceValue `json:"shadowed,omitempty"`
+}
+
+type SourceValue struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+// Recorder receives provenance of each maintained field
+type Recorder func(provenance Provenance)
+
+// provenance must be built before the value is applied, so value field has before processing is reported for Literal
+func (f *Field) provenance(sources []Source, winner int, source string, value string) Provenance {
+	p := Provenance{
+		Path:   f.Path,
+		Source: source,
+		Value:  f.Redact(value),
+		Env:    f.EnvVarName,
+		Key:    strings.Join(f.FileKeys, "."),
+	}
+	for _, name := range []string{f.ArgName, f.ShortArgName} {
+		if name != "" {
+			p.Args = append(p.Args, name)
+		}
+	}
+	if source == SourceLiteral {
+		p.Value = f.Redact(f.literalString())
+	}
+	if winner < 0 {
+		return p
+	}
+	rest := sources[winner:]
+	if source != SourceValueFile {
+		// value file is consulted before the source at winner position, so that source is shadowed by it
+		rest = sources[winner+1:]
+	}
+	valueFileAt := valueFilePosition(sources)
+	for i, src := range rest {
+		if source != SourceValueFile && len(sources)-len(rest)+i == valueFileAt {
+			f.shadowValueFile(&p)
+		}
+		if _, ok := src.(LiteralSource); ok {
+			if !f.Elem.IsZero() {
+				p.Shadowed = append(p.Shadowed, SourceValue{Source: SourceLiteral, Value: f.Redact(f.literalString())})
+			}
+			continue
+		}
+		if shadowed, found, err := src.Lookup(&f.FieldInfo); err == nil && found {
+			p.Shadowed = append(p.Shadowed, SourceValue{Source: src.Name(), Value: f.Redact(shadowed)})
+		}
+	}
+	if source != SourceValueFile && valueFileAt == len(sources) {
+		f.shadowValueFile(&p)
+	}
+	return p
+}
+
+func (f *Field) shadowValueFile(p *Provenance) {
+	if shadowed, found, err := f.lookupValueFile(); err == nil && found {
+		p.Shadowed = append(p.Shadowed, SourceValue{Source: SourceValueFile, Value: f.Redact(shadowed)})
+	}
+}
+
+func (f *Field) literalString() string {
+	return fmt.Sprint(f.Elem.Interface())
+}
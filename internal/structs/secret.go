@@ -0,0 +1,90 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedactedValue replaces values of secret fields wherever they are rendered
+const RedactedValue = "******"
+
+// SecretValue is implemented by pointers to wrappers which keep the value secret, e.g. configrant.Secret.
+// Field of such type is secret, the wrapped value is maintained in place of the wrapper
+type SecretValue interface {
+	SecretElem() reflect.Value
+}
+
+var secretValueType = reflect.TypeOf((*SecretValue)(nil)).Elem()
+
+func isSecretType(typ reflect.Type) bool {
+	return reflect.PtrTo(typ).Implements(secretValueType)
+}
+
+// secretElemOf returns value wrapped by secret wrapper or elem itself if it isn't a wrapper
+func secretElemOf(elem reflect.Value) (reflect.Value, bool) {
+	if !elem.CanAddr() || !isSecretType(elem.Type()) {
+		return elem, false
+	}
+	return elem.Addr().Interface().(SecretValue).SecretElem(), true
+}
+
+// Redact returns RedactedValue instead of non-empty value of secret field
+func (f *FieldInfo) Redact(value string) string {
+	if !f.Secret || value == "" {
+		return value
+	}
+	return RedactedValue
+}
+
+// redactError masks raw value and value field holds in error message of secret field,
+// underlying errors stay reachable with errors.Is and errors.As
+func (f *Field) redactError(err error, raw string) error {
+	if !f.Secret || err == nil {
+		return err
+	}
+	secrets := []string{raw}
+	for _, delimiter := range append(append([]string{}, f.Delimiters...), f.KeyValueSeparator) {
+		for _, secret := range secrets {
+			if parts := strings.Split(secret, delimiter); len(parts) > 1 {
+				secrets = append(secrets, parts...)
+			}
+		}
+	}
+	if !f.Elem.IsZero() {
+		secrets = append(secrets, fmt.Sprint(f.Elem.Interface()))
+	}
+	_ = eachElem(f.Elem, func(elem reflect.Value) error {
+		if !elem.IsZero() {
+			secrets = append(secrets, fmt.Sprint(elem.Interface()))
+		}
+		return nil
+	})
+	for _, secret := range secrets {
+		secrets = append(secrets, strconv.Quote(secret))
+	}
+	// longer secrets go first, so their parts don't break replacement
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return &redactedError{err: err, secrets: secrets}
+}
+
+type redactedError struct {
+	err     error
+	secrets []string
+}
+
+func (e *redactedError) Error() string {
+	msg := e.err.Error()
+	for _, secret := range e.secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, RedactedValue)
+		}
+	}
+	return msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,98 @@
+package structs
+
+import (
+	"reflect"
+)
+
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func Clone(from reflect.Value) reflect.Value {
+	to := reflect.New(from.Type()).Elem()
+	to.Set(from)
+	cloneInto(to)
+	return to
+}
+
+// cloneInto replaces pointers reachable through settable fields with pointers to copies,
+// so clone can be maintained without touching the original value. Slices and maps are
+// always replaced as a whole by setters, so they can be shared safely
+func cloneInto(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		elem := reflect.New(v.Type().Elem())
+		elem.Elem().Set(v.Elem())
+		cloneInto(elem.Elem())
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			cloneInto(v.Field(i))
+		}
+	}
+}
+
+// Diff lists fields which values differ in the order they are maintained, fields of slices and maps of structs
+// elements are compared one by one. Values of secret fields and of fields which reveal them (see Values) are redacted
+func Diff(from Parser, to Parser) ([]Change, error) {
+	oldValues, err := from.Values()
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := to.Values()
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]*Value, len(oldValues))
+	for i := range oldValues {
+		removed[oldValues[i].Field.Path] = &oldValues[i]
+	}
+	changes := make([]Change, 0)
+	for i := range newValues {
+		path := newValues[i].Field.Path
+		changes = appendChange(changes, path, removed[path], &newValues[i])
+		delete(removed, path)
+	}
+	for i := range oldValues {
+		if value, ok := removed[oldValues[i].Field.Path]; ok {
+			changes = appendChange(changes, value.Field.Path, value, nil)
+		}
+	}
+	return changes, nil
+}
+
+// appendChange appends change of the field if its values differ, nil value means element doesn't exist
+func appendChange(changes []Change, path string, from *Value, to *Value) []Change {
+	oldValue, newValue := from.interfaceOf(), to.interfaceOf()
+	if reflect.DeepEqual(oldValue, newValue) {
+		return changes
+	}
+	if from.isSecret() || to.isSecret() {
+		oldValue, newValue = from.redacted(), to.redacted()
+	}
+	return append(changes, Change{Path: path, Old: oldValue, New: newValue})
+}
+
+func (v *Value) interfaceOf() interface{} {
+	if v == nil {
+		return nil
+	}
+	return v.Field.Elem.Interface()
+}
+
+func (v *Value) isSecret() bool {
+	return v != nil && v.Field.Secret
+}
+
+// redacted returns RedactedValue instead of non-zero value
+func (v *Value) redacted() interface{} {
+	if v == nil || v.Field.Elem.IsZero() {
+		return v.interfaceOf()
+	}
+	return RedactedValue
+}
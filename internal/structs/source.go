@@ -0,0 +1,45 @@
+package structs
+
+const (
+	SourceLiteral   = "literal"
+	SourceValueFile = "value-file"
+)
+
+type Source interface {
+	Name() string
+	Lookup(field *FieldInfo) (value string, found bool, err error)
+}
+
+type Preparer interface {
+	Prepare() error
+}
+
+// Enumerator is implemented by sources which discover elements of slices and maps of structs:
+// Keys returns indices or map keys the source has element values for
+type Enumerator interface {
+	Keys(field *FieldInfo) (keys []string, err error)
+}
+
+// LiteralSource marks position of the value the field has before processing in sources order,
+// non-zero value wins over all following sources. It is handled by Field itself, so Lookup finds nothing
+type LiteralSource struct{}
+
+func (LiteralSource) Name() string {
+	return SourceLiteral
+}
+
+func (LiteralSource) Lookup(field *FieldInfo) (string, bool, error) {
+	return "", false, nil
+}
+
+// DefaultSource takes values from default option. File given by 'file' option is consulted by Field itself
+// right before DefaultSource or after all sources if there is no DefaultSource, so it works with any sources order
+type DefaultSource struct{}
+
+func (DefaultSource) Name() string {
+	return "default"
+}
+
+func (DefaultSource) Lookup(field *FieldInfo) (string, bool, error) {
+	return field.DefaultValue, field.DefaultValue != "", nil
+}
@@ -7,10 +7,22 @@ import (
 
 var ErrNotPtrStruct = errors.New("configuration must be a pointer to a struct")
 
+// DefaultDelimiters separate elements of collections from the outermost level to the innermost one
+var DefaultDelimiters = []string{";", "|", "~"}
+
 type Parser struct {
-	ValueOf reflect.Value
-	ElemOf  reflect.Value
-	TypeOf  reflect.Type
+	ValueOf    reflect.Value
+	ElemOf     reflect.Value
+	TypeOf     reflect.Type
+	Naming     Naming
+	Converters Converters
+	Delimiters []string
+	// Recorder receives provenance of each maintained field if set
+	Recorder Recorder
+	// Expansion configures expansion of references in raw values
+	Expansion Expansion
+	// expander is shared by parsers of one MaintainFields call
+	expander *expander
 }
 
 func NewParser(from interface{}) (Parser, error) {
@@ -25,42 +37,93 @@ func NewParser(from interface{}) (Parser, error) {
 	}
 	typeOf := elemOf.Type()
 	cfg = Parser{
-		valueOf,
-		elemOf,
-		typeOf,
+		ValueOf:    valueOf,
+		ElemOf:     elemOf,
+		TypeOf:     typeOf,
+		Delimiters: DefaultDelimiters,
 	}
 	return cfg, nil
 }
 
-func (cfg Parser) MaintainFields() error {
-	fields, err := cfg.collectConfigFields()
+func (cfg Parser) MaintainFields(sources []Source) error {
+	cfg.expander = newExpander(cfg, sources)
+	errs, err := cfg.maintain(nil, sources)
 	if err != nil {
 		return err
 	}
-	for _, field := range fields {
-		field.Set()
+	return errs.ErrorOrNil()
+}
+
+func (cfg Parser) maintain(parent *Field, sources []Source) (Errors, error) {
+	fields, err := cfg.collectConfigFields(parent)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	var errs Errors
+	for i := range fields {
+		field := &fields[i]
+		if field.TagErr != nil {
+			errs = append(errs, &FieldError{Path: field.Path, Err: field.TagErr})
+			continue
+		}
+		if field.IsCollection() {
+			collectionErrs, err := cfg.maintainCollection(field, sources)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, collectionErrs...)
+			continue
+		}
+		if err := field.set(sources, cfg.Recorder, cfg.expander); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs, nil
 }
 
-func (cfg Parser) collectConfigFields() ([]Field, error) {
+func (cfg Parser) Fields() ([]Field, error) {
+	return cfg.collectConfigFields(nil)
+}
+
+func (cfg Parser) collectConfigFields(parent *Field) ([]Field, error) {
 	fields := make([]Field, 0)
 	for i := 0; i < cfg.ElemOf.NumField(); i++ {
-		field := NewField(cfg.TypeOf.Field(i), cfg.ElemOf.Field(i))
+		field := NewField(parent, cfg.TypeOf.Field(i), cfg.ElemOf.Field(i))
+		field.Converters = cfg.Converters
+		field.Delimiters = cfg.Delimiters
+		if field.Separator != "" && len(cfg.Delimiters) > 0 {
+			field.Delimiters = append([]string{field.Separator}, cfg.Delimiters[1:]...)
+		}
 		switch {
 		case !field.IsConfigurable:
 			continue
+		case field.TagErr != nil:
+			// reported on maintenance, so every malformed tag is listed in errors
+			fields = append(fields, field)
 		case field.IsStruct():
 			subcfg, err := NewParser(field.Elem.Addr().Interface())
 			if err != nil {
 				return nil, err
 			}
-			substructureFields, err := subcfg.collectConfigFields()
+			subcfg.Naming = cfg.Naming
+			subcfg.Converters = cfg.Converters
+			subcfg.Delimiters = cfg.Delimiters
+			substructureFields, err := subcfg.collectConfigFields(&field)
 			if err != nil {
 				return nil, err
 			}
 			fields = append(fields, substructureFields...)
+		case field.IsCollection():
+			// elements are discovered from sources on maintenance, so collection itself is listed
+			cfg.Naming.apply(&field)
+			elementEnvNames, elementBoolArgs, err := cfg.elementNames(&field)
+			if err != nil {
+				return nil, err
+			}
+			field.ElementEnvNames, field.ElementBoolArgs = elementEnvNames, elementBoolArgs
+			fields = append(fields, field)
 		default:
+			cfg.Naming.apply(&field)
 			fields = append(fields, field)
 		}
 	}
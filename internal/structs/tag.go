@@ -0,0 +1,179 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrInvalidTag = errors.New("invalid cfgrant tag")
+
+type tagOption struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// tokenizeTag splits tag into options separated by comma, each option is either name or name:value.
+// Value enclosed in single quotes is taken as is, so it may contain commas and leading or trailing spaces,
+// \' stands for quote inside of it. Unquoted value is trimmed, \, and \' stand for comma and quote in it
+func tokenizeTag(tag string) ([]tagOption, error) {
+	options := make([]tagOption, 0)
+	i := 0
+	for {
+		start := i
+		for i < len(tag) && tag[i] != ':' && tag[i] != ',' {
+			i++
+		}
+		option := tagOption{name: strings.TrimSpace(tag[start:i])}
+		if option.name == "" {
+			return nil, fmt.Errorf("%w: option name is missing at position %d", ErrInvalidTag, start)
+		}
+		if i < len(tag) && tag[i] == ':' {
+			value, next, err := scanTagValue(tag, i+1)
+			if err != nil {
+				return nil, err
+			}
+			option.value, option.hasValue, i = value, true, next
+		}
+		options = append(options, option)
+		if i >= len(tag) {
+			return options, nil
+		}
+		i++
+	}
+}
+
+// scanTagValue reads option value which starts at position i and returns position of the comma which terminates it
+func scanTagValue(tag string, i int) (string, int, error) {
+	var sb strings.Builder
+	for i < len(tag) && tag[i] == ' ' {
+		i++
+	}
+	if i < len(tag) && tag[i] == '\'' {
+		start := i
+		for i++; i < len(tag); i++ {
+			switch {
+			case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == '\'':
+				sb.WriteByte('\'')
+				i++
+			case tag[i] == '\'':
+				i++
+				for i < len(tag) && tag[i] == ' ' {
+					i++
+				}
+				if i < len(tag) && tag[i] != ',' {
+					return "", i, fmt.Errorf("%w: unexpected character %q after closing quote at position %d", ErrInvalidTag, tag[i], i)
+				}
+				return sb.String(), i, nil
+			default:
+				sb.WriteByte(tag[i])
+			}
+		}
+		return "", i, fmt.Errorf("%w: quote opened at position %d is not closed", ErrInvalidTag, start)
+	}
+	for ; i < len(tag) && tag[i] != ','; i++ {
+		if tag[i] == '\\' && i+1 < len(tag) && (tag[i+1] == ',' || tag[i+1] == '\'') {
+			i++
+		}
+		sb.WriteByte(tag[i])
+	}
+	return strings.TrimSpace(sb.String()), i, nil
+}
+
+// splitEscaped splits value by separator which isn't preceded by backslash,
+// backslash is removed from escaped separators only, so nested levels keep their escapes
+func splitEscaped(value string, sep string) []string {
+	parts := make([]string, 0)
+	var part strings.Builder
+	for i := 0; i < len(value); {
+		switch {
+		case value[i] == '\\' && strings.HasPrefix(value[i+1:], sep):
+			part.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(value[i:], sep):
+			parts = append(parts, part.String())
+			part.Reset()
+			i += len(sep)
+		default:
+			part.WriteByte(value[i])
+			i++
+		}
+	}
+	return append(parts, part.String())
+}
+
+// cutEscaped splits value around the first separator which isn't preceded by backslash,
+// escaped separators are unescaped in the part before it only
+func cutEscaped(value string, sep string) (before string, after string, found bool) {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && strings.HasPrefix(value[i+1:], sep):
+			i += len(sep)
+		case strings.HasPrefix(value[i:], sep):
+			return strings.ReplaceAll(value[:i], `\`+sep, sep), value[i+len(sep):], true
+		}
+	}
+	return value, "", false
+}
+
+// EscapeDelimiters prefixes each occurrence of delimiters in value with backslash,
+// so value is kept as a single element when it is joined into collection
+func EscapeDelimiters(value string, delimiters ...string) string {
+	for _, delimiter := range delimiters {
+		if delimiter != "" {
+			value = strings.ReplaceAll(value, delimiter, `\`+delimiter)
+		}
+	}
+	return value
+}
+
+// Tag is a parsed cfgrant tag. It is exposed for static analysis, so tags are checked by the same parser as on processing
+type Tag struct {
+	Default    string
+	Env        string
+	Arg        string
+	Short      string
+	Key        string
+	Positional bool
+	// Problems don't prevent field maintenance: unknown or duplicated options, empty names
+	Problems []error
+
+	opts tagOptions
+}
+
+// ParseTag parses value of cfgrant tag, error is returned for malformed tag
+func ParseTag(tag string) (Tag, error) {
+	opts, err := parseConfigrantTag(tag)
+	if err != nil {
+		return Tag{}, err
+	}
+	return Tag{
+		Default:    opts.def,
+		Env:        opts.env,
+		Arg:        opts.arg,
+		Short:      opts.short,
+		Key:        opts.key,
+		Positional: opts.positional,
+		Problems:   opts.problems,
+		opts:       opts,
+	}, nil
+}
+
+// CheckDefault converts default value into typ the same way it is done on processing, type must not be a pointer
+func (t Tag) CheckDefault(typ reflect.Type) error {
+	delimiters := DefaultDelimiters
+	if t.opts.sep != "" {
+		delimiters = append([]string{t.opts.sep}, DefaultDelimiters[1:]...)
+	}
+	kvsep := t.opts.kvsep
+	if kvsep == "" {
+		kvsep = DefaultKeyValueSeparator
+	}
+	setter, err := determineFieldSetter(typ, setterOptions{layout: t.opts.layout, delimiters: delimiters, kvsep: kvsep})
+	if err != nil {
+		return err
+	}
+	return setter.Apply(reflect.New(typ).Elem(), t.Default)
+}
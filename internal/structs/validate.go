@@ -0,0 +1,298 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalid = errors.New("invalid value")
+
+type Validator func(value interface{}) error
+
+type ValidationRules struct {
+	Min        string
+	Max        string
+	OneOf      []string
+	Pattern    string
+	Validators []string
+}
+
+type ValidationError struct {
+	Rule string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Rule, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+type validatable interface {
+	Validate() error
+}
+
+// ValidateFields checks rules of all fields, fields reported in failed are skipped
+// as well as Validate() methods of structs which contain them, so that validation
+// errors can be merged with errors of maintaining
+func (cfg Parser) ValidateFields(validators map[string]Validator, failed Errors) error {
+	errs, err := cfg.validate(nil, validators, failed)
+	if err != nil {
+		return err
+	}
+	return append(failed, errs...).ErrorOrNil()
+}
+
+func (cfg Parser) validate(parent *Field, validators map[string]Validator, failed Errors) (Errors, error) {
+	fields, err := cfg.collectConfigFields(parent)
+	if err != nil {
+		return nil, err
+	}
+	var errs Errors
+	for i := range fields {
+		field := &fields[i]
+		if field.TagErr != nil || failed.covers(field.Path) {
+			continue
+		}
+		for _, err := range field.Validate(validators) {
+			errs = append(errs, &FieldError{Path: field.Path, Err: field.redactError(err, "")})
+		}
+		if !field.IsCollection() {
+			continue
+		}
+		err := eachCollectionElement(field, func(key string, elem reflect.Value) error {
+			sub, element := cfg.elementParser(field, key, elem)
+			elementErrs, err := sub.validate(&element, validators, failed)
+			errs = append(errs, elementErrs...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	path := ""
+	if parent != nil {
+		path = parent.Path
+	}
+	errs = append(errs, validateStructs(path, cfg.ElemOf, cfg.Converters, failed)...)
+	return errs, nil
+}
+
+func (f *Field) Validate(validators map[string]Validator) []error {
+	rules := f.Rules
+	errs := make([]error, 0)
+	if rules.Min != "" {
+		if err := compareBound(f.Elem, rules.Min, true); err != nil {
+			errs = append(errs, &ValidationError{Rule: "min", Err: err})
+		}
+	}
+	if rules.Max != "" {
+		if err := compareBound(f.Elem, rules.Max, false); err != nil {
+			errs = append(errs, &ValidationError{Rule: "max", Err: err})
+		}
+	}
+	if len(rules.OneOf) > 0 {
+		if err := eachElem(f.Elem, func(elem reflect.Value) error { return oneOf(elem, rules.OneOf, f.setterOptions()) }); err != nil {
+			errs = append(errs, &ValidationError{Rule: "oneof", Err: err})
+		}
+	}
+	if rules.Pattern != "" {
+		if err := matchPattern(f.Elem, rules.Pattern); err != nil {
+			errs = append(errs, &ValidationError{Rule: "pattern", Err: err})
+		}
+	}
+	for _, name := range rules.Validators {
+		validator, ok := validators[name]
+		if !ok {
+			errs = append(errs, &ValidationError{Rule: name, Err: fmt.Errorf("validator is not registered")})
+			continue
+		}
+		if err := validator(f.Elem.Interface()); err != nil {
+			errs = append(errs, &ValidationError{Rule: name, Err: err})
+		}
+	}
+	return errs
+}
+
+// validateStructs calls Validate method of configuration struct and all its substructures, deepest first
+func validateStructs(path string, elemOf reflect.Value, converters Converters, failed Errors) Errors {
+	var errs Errors
+	for i := 0; i < elemOf.NumField(); i++ {
+		typeOfField := elemOf.Type().Field(i)
+		fieldOf := elemOf.Field(i)
+		for fieldOf.Kind() == reflect.Ptr && !fieldOf.IsNil() {
+			fieldOf = fieldOf.Elem()
+		}
+		if typeOfField.PkgPath != "" || typeOfField.Tag.Get("cfgrant") == "-" || fieldOf.Kind() != reflect.Struct || converters.IsDecodable(fieldOf.Type()) {
+			continue
+		}
+		errs = append(errs, validateStructs(joinPath(path, typeOfField.Name), fieldOf, converters, failed)...)
+	}
+	if failed.contain(path) {
+		return errs
+	}
+	if v, ok := elemOf.Addr().Interface().(validatable); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, &FieldError{Path: path, Err: &ValidationError{Rule: "Validate", Err: err}})
+		}
+	}
+	return errs
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func compareBound(elem reflect.Value, bound string, isMin bool) error {
+	var cmp int
+	switch {
+	case isTimeDurationType(elem.Type()):
+		boundDuration, err := time.ParseDuration(bound)
+		if err != nil {
+			return fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		cmp = compareInts(elem.Int(), int64(boundDuration))
+	case isIntKind(elem.Kind()):
+		boundInt, err := strconv.ParseInt(bound, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		cmp = compareInts(elem.Int(), boundInt)
+	case isUintKind(elem.Kind()):
+		boundUint, err := strconv.ParseUint(bound, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		cmp = compareUints(elem.Uint(), boundUint)
+	case elem.Kind() == reflect.Float32 || elem.Kind() == reflect.Float64:
+		boundFloat, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		cmp = compareFloats(elem.Float(), boundFloat)
+	case elem.Kind() == reflect.String, elem.Kind() == reflect.Slice, elem.Kind() == reflect.Map, elem.Kind() == reflect.Array:
+		boundLen, err := strconv.Atoi(bound)
+		if err != nil {
+			return fmt.Errorf("invalid length bound %q: %w", bound, err)
+		}
+		length := elem.Len()
+		if elem.Kind() == reflect.String {
+			length = utf8.RuneCountInString(elem.String())
+		}
+		if isMin && length < boundLen {
+			return fmt.Errorf("length %d must be at least %d", length, boundLen)
+		}
+		if !isMin && length > boundLen {
+			return fmt.Errorf("length %d must be at most %d", length, boundLen)
+		}
+		return nil
+	default:
+		return fmt.Errorf("bounds are not supported for type %s", elem.Type())
+	}
+	if isMin && cmp < 0 {
+		return fmt.Errorf("value %v must be at least %s", elem.Interface(), bound)
+	}
+	if !isMin && cmp > 0 {
+		return fmt.Errorf("value %v must be at most %s", elem.Interface(), bound)
+	}
+	return nil
+}
+
+func oneOf(elem reflect.Value, options []string, opts setterOptions) error {
+	setter, err := determineFieldSetter(elem.Type(), opts)
+	if err != nil {
+		return err
+	}
+	for _, option := range options {
+		optionValue := reflect.New(elem.Type()).Elem()
+		if err := setter.Apply(optionValue, option); err != nil {
+			return fmt.Errorf("invalid option %q: %w", option, err)
+		}
+		if reflect.DeepEqual(elem.Interface(), optionValue.Interface()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("value %v must be one of %s", elem.Interface(), strings.Join(options, ", "))
+}
+
+func matchPattern(elem reflect.Value, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return eachElem(elem, func(elem reflect.Value) error {
+		if elem.Kind() != reflect.String {
+			return fmt.Errorf("pattern is not supported for type %s", elem.Type())
+		}
+		if !re.MatchString(elem.String()) {
+			return fmt.Errorf("value %q must match %s", elem.String(), pattern)
+		}
+		return nil
+	})
+}
+
+// eachElem applies check to each slice or array element or to the value itself if it isn't a slice or an array
+func eachElem(elem reflect.Value, check func(elem reflect.Value) error) error {
+	if elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array {
+		return check(elem)
+	}
+	for i := 0; i < elem.Len(); i++ {
+		if err := check(elem.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
+
+func compareInts(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUints(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
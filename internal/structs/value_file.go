@@ -0,0 +1,51 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// MaxValueFileSize limits size of files field values are read from
+const MaxValueFileSize = 1 << 20
+
+// lookupValueFile reads value from file given by 'file' option, file which doesn't exist has no value
+func (f *FieldInfo) lookupValueFile() (string, bool, error) {
+	if f.ValueFile == "" {
+		return "", false, nil
+	}
+	value, err := ReadValueFile(f.ValueFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+// ReadValueFile reads field value from file, e.g. secret mounted by Docker or Kubernetes.
+// Single trailing newline is trimmed, since such files are usually written with it
+func ReadValueFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("value file is unreadable: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("value file is unreadable: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("value file %s is a directory", path)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, MaxValueFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("value file %s is unreadable: %w", path, err)
+	}
+	if len(data) > MaxValueFileSize {
+		return "", fmt.Errorf("value file %s exceeds %d bytes", path, MaxValueFileSize)
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
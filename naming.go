@@ -0,0 +1,57 @@
+package configrant
+
+import (
+	"strings"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// NameCase joins words of the field path into environment variable or command line argument name
+type NameCase = structs.NameCase
+
+// ScreamingSnakeCase joins words as SUBSTRUCT_SUBNAME, it is used for environment variables by default
+func ScreamingSnakeCase(words []string) string {
+	return strings.ToUpper(strings.Join(words, "_"))
+}
+
+// SnakeCase joins words as substruct_subname
+func SnakeCase(words []string) string {
+	return strings.ToLower(strings.Join(words, "_"))
+}
+
+// KebabCase joins words as substruct-subname, it is used for command line arguments by default
+func KebabCase(words []string) string {
+	return strings.ToLower(strings.Join(words, "-"))
+}
+
+// WithAutoEnv derives environment variable names from Go field path for fields without env option,
+// e.g. Substruct.Subname -> APP_SUBSTRUCT_SUBNAME for prefix APP. Empty prefix means no prefix.
+// Path segment of the nested struct can be replaced with prefix option of the struct field
+func WithAutoEnv(prefix string) Option {
+	return func(l *Loader) {
+		l.naming.AutoEnv = true
+		l.naming.EnvPrefix = prefix
+	}
+}
+
+// WithEnvCase sets case of derived environment variable names
+func WithEnvCase(nameCase NameCase) Option {
+	return func(l *Loader) {
+		l.naming.EnvCase = nameCase
+	}
+}
+
+// WithAutoArgs derives command line argument names from Go field path for fields without arg option,
+// e.g. Substruct.Subname -> --substruct-subname
+func WithAutoArgs() Option {
+	return func(l *Loader) {
+		l.naming.AutoArgs = true
+	}
+}
+
+// WithArgCase sets case of derived command line argument names
+func WithArgCase(nameCase NameCase) Option {
+	return func(l *Loader) {
+		l.naming.ArgCase = nameCase
+	}
+}
@@ -0,0 +1,81 @@
+package configrant
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// FieldReport describes where the final value of the field comes from: Go field path, winning source
+// and its raw value, environment variable, command line arguments and configuration file key the field
+// is looked up by and values of lower-priority sources which have been shadowed by the winning one.
+// Source is empty if no source has value for the field
+type FieldReport = structs.Provenance
+
+// SourceValue is a raw value found by the source
+type SourceValue = structs.SourceValue
+
+// Report lists provenance of every maintained field in the order fields are processed,
+// elements of slices and maps of structs are listed with their own paths, e.g. Upstreams[0].Host
+type Report struct {
+	Fields []FieldReport `json:"fields"`
+}
+
+// Lookup returns provenance of the field with given path
+func (r Report) Lookup(path string) (FieldReport, bool) {
+	for _, field := range r.Fields {
+		if field.Path == path {
+			return field, true
+		}
+	}
+	return FieldReport{}, false
+}
+
+// String renders report as plain-text table
+func (r Report) String() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tSOURCE\tVALUE\tENV\tARG\tSHADOWED")
+	for _, field := range r.Fields {
+		shadowed := make([]string, 0, len(field.Shadowed))
+		for _, sv := range field.Shadowed {
+			shadowed = append(shadowed, fmt.Sprintf("%s=%q", sv.Source, sv.Value))
+		}
+		row := []string{
+			field.Path,
+			field.Source,
+			field.Value,
+			field.Env,
+			strings.Join(field.Args, ", "),
+			strings.Join(shadowed, ", "),
+		}
+		if field.Source != "" {
+			row[2] = fmt.Sprintf("%q", field.Value)
+		}
+		for i := range row {
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// ProcessWithReport maintains configuration like Process and reports which source supplied value of each field.
+// Report is returned along with processing errors, so fields which couldn't be maintained are listed as well
+func (l *Loader) ProcessWithReport(from interface{}) (Report, error) {
+	var report Report
+	_, err := l.process(from, func(provenance structs.Provenance) {
+		report.Fields = append(report.Fields, provenance)
+	})
+	return report, err
+}
+
+// ProcessWithReport maintains configuration and reports which source supplied value of each field using default Loader
+func ProcessWithReport(from interface{}) (Report, error) {
+	return New().ProcessWithReport(from)
+}
@@ -0,0 +1,56 @@
+package configrant
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// RedactedValue replaces values of secret fields in errors, usage, reports and dumps
+const RedactedValue = structs.RedactedValue
+
+// Secret wraps configuration value which must never be revealed. It is rendered as RedactedValue by fmt
+// (whatever verb is used), encoding/json and encoders relying on encoding.TextMarshaler, use Value to get
+// the wrapped value. Field of Secret type is maintained like field of the wrapped type and is secret
+// even if it isn't tagged with 'secret' option
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret wraps value, e.g. to initialize Secret field before processing
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the wrapped value
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+func (s Secret[T]) String() string {
+	return RedactedValue
+}
+
+func (s Secret[T]) GoString() string {
+	return RedactedValue
+}
+
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	io.WriteString(f, RedactedValue)
+}
+
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RedactedValue)
+}
+
+func (s Secret[T]) MarshalText() ([]byte, error) {
+	return []byte(RedactedValue), nil
+}
+
+// SecretElem returns the wrapped value for configrant to maintain it in place of the wrapper
+func (s *Secret[T]) SecretElem() reflect.Value {
+	return reflect.ValueOf(&s.value).Elem()
+}
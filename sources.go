@@ -0,0 +1,349 @@
+package configrant
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/umalmyha/configrant/internal/cfgargs"
+	"github.com/umalmyha/configrant/internal/cfgfile"
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// Source provides raw string values for configuration fields. Lookup reports whether value has been found,
+// so next source in order is consulted only if previous ones have nothing to offer for the field
+type Source = structs.Source
+
+// Preparer is implemented by sources which must load their data before lookups, e.g. read a file.
+// Prepare is called each time configuration is processed on the source passed to Loader, so Loader
+// which has Preparer sources must not be used by several goroutines concurrently, see Binder
+type Preparer = structs.Preparer
+
+// Binder is implemented by sources which keep state of a single Process call, e.g. data read from a file.
+// Bind is called each time configuration is processed and returns source used by this call only, so source
+// passed to Loader stays untouched and Loader can be used by several goroutines concurrently. Bind takes
+// precedence over Prepare
+type Binder interface {
+	Bind() (Source, error)
+}
+
+// Enumerator is implemented by sources which discover elements of slices and maps of structs.
+// Keys returns indices or map keys the source has element values for
+type Enumerator = structs.Enumerator
+
+// FieldInfo holds field metadata which is passed to Source on lookup
+type FieldInfo = structs.FieldInfo
+
+// Args returns source which takes values from command line arguments (arg option)
+func Args() Source {
+	return argsSource{}
+}
+
+// Env returns source which takes values from environment variables (env option)
+func Env() Source {
+	return envSource{}
+}
+
+// File returns source which takes values from the configuration file located by path.
+// JSON, YAML and TOML formats are supported, format is detected by file extension
+func File(path string) Source {
+	return &fileSource{path: func(*processing) string { return path }}
+}
+
+// ConfigFile returns source which takes values from the configuration file located by
+// command line argument ConfigFileArg or environment variable ConfigFileEnv. Nothing is found if neither is set
+func ConfigFile() Source {
+	return &fileSource{path: configFilePath, arg: ConfigFileArg}
+}
+
+// Literal returns source which stands for the value field has before processing (e.g. set in struct literal).
+// If this value is non-zero, it wins over all sources following Literal in order
+func Literal() Source {
+	return structs.LiteralSource{}
+}
+
+// Defaults returns source which takes values from default option. File given by 'file' option
+// is consulted right before it, or after all sources if Defaults isn't used
+func Defaults() Source {
+	return structs.DefaultSource{}
+}
+
+// processing holds state of a single Process call: parsed command line arguments and environment
+type processing struct {
+	args *cfgargs.Args
+	env  environment
+}
+
+// binder is implemented by built-in sources which depend on processing state. Source passed to Loader is never
+// modified, bind returns its copy bound to the state, so the same Loader can process configurations concurrently
+type binder interface {
+	bind(p *processing) (Source, error)
+}
+
+// environment looks up environment variables, names lists all of them and is nil if they can't be listed
+type environment struct {
+	lookup func(name string) (string, bool)
+	names  func() []string
+}
+
+// environmentProvider returns environment for processing, nil provider stands for the process environment
+type environmentProvider func() (environment, error)
+
+func (p environmentProvider) get() (environment, error) {
+	if p == nil {
+		return osEnvironment(), nil
+	}
+	return p()
+}
+
+func osEnvironment() environment {
+	return environment{lookup: os.LookupEnv, names: func() []string { return environNames(os.Environ()) }}
+}
+
+func environNames(environ []string) []string {
+	names := make([]string, 0, len(environ))
+	for _, env := range environ {
+		names = append(names, strings.SplitN(env, "=", 2)[0])
+	}
+	return names
+}
+
+func mapEnvironment(env map[string]string) environment {
+	return environment{
+		lookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		names: func() []string {
+			names := make([]string, 0, len(env))
+			for name := range env {
+				names = append(names, name)
+			}
+			return names
+		},
+	}
+}
+
+// fallback returns environment which consults other for variables missing in e
+func (e environment) fallback(other environment) environment {
+	env := environment{
+		lookup: func(name string) (string, bool) {
+			if value, ok := e.lookup(name); ok {
+				return value, ok
+			}
+			return other.lookup(name)
+		},
+	}
+	if e.names != nil || other.names != nil {
+		env.names = func() []string {
+			names := make([]string, 0)
+			for _, list := range []func() []string{e.names, other.names} {
+				if list != nil {
+					names = append(names, list()...)
+				}
+			}
+			return names
+		}
+	}
+	return env
+}
+
+type argsSource struct {
+	args *cfgargs.Args
+}
+
+func (argsSource) Name() string {
+	return "arg"
+}
+
+func (s argsSource) bind(p *processing) (Source, error) {
+	return argsSource{args: p.args}, nil
+}
+
+func (s argsSource) Lookup(field *FieldInfo) (string, bool, error) {
+	if field.Positional {
+		positional := s.args.Positional()
+		delimiter := listDelimiter(field)
+		escaped := make([]string, len(positional))
+		for i, arg := range positional {
+			escaped[i] = structs.EscapeDelimiters(arg, delimiter)
+		}
+		return strings.Join(escaped, delimiter), len(positional) > 0, nil
+	}
+	for _, name := range []string{field.ArgName, field.ShortArgName} {
+		if name == "" {
+			continue
+		}
+		if value := s.args.Lookup(name); value != "" {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Keys discovers elements by flags nested under collection flag, e.g. --upstreams.0.host
+func (s argsSource) Keys(field *FieldInfo) ([]string, error) {
+	if field.ArgName == "" {
+		return nil, nil
+	}
+	prefix := cfgargs.Normalize(field.ArgName) + "."
+	keys := make([]string, 0)
+	for _, name := range s.args.Names() {
+		if rest := strings.TrimPrefix(name, prefix); rest != name {
+			if i := strings.Index(rest, "."); i > 0 {
+				keys = append(keys, rest[:i])
+			}
+		}
+	}
+	return keys, nil
+}
+
+type envSource struct {
+	env environment
+}
+
+func (envSource) Name() string {
+	return "env"
+}
+
+func (s envSource) bind(p *processing) (Source, error) {
+	return envSource{env: p.env}, nil
+}
+
+func (s envSource) environment() environment {
+	if s.env.lookup == nil {
+		return osEnvironment()
+	}
+	return s.env
+}
+
+// Lookup consults the variable itself and then file pointed by the variable with _FILE suffix (e.g. DB_PASSWORD_FILE)
+func (s envSource) Lookup(field *FieldInfo) (string, bool, error) {
+	if field.EnvVarName == "" {
+		return "", false, nil
+	}
+	env := s.environment()
+	if value, _ := env.lookup(field.EnvVarName); value != "" {
+		return value, true, nil
+	}
+	if path, _ := env.lookup(field.EnvVarName + EnvFileSuffix); path != "" {
+		value, err := structs.ReadValueFile(path)
+		return value, err == nil, err
+	}
+	return "", false, nil
+}
+
+// Keys discovers elements by variables nested under collection variable, e.g. UPSTREAMS_0_HOST.
+// Map keys are lower-cased, since environment variables are upper-cased by convention. Key is told apart from
+// element field name by names of element variables, so key may contain underscore, variable which doesn't belong
+// to any element field is skipped and variable which can be split in several ways is reported.
+// Nothing is discovered if environment can't list variable names
+func (s envSource) Keys(field *FieldInfo) ([]string, error) {
+	env := s.environment()
+	if field.EnvVarName == "" || env.names == nil {
+		return nil, nil
+	}
+	names := env.names()
+	sort.Strings(names)
+	prefix := field.EnvVarName + "_"
+	keys := make([]string, 0)
+	for _, name := range names {
+		rest := strings.TrimPrefix(name, prefix)
+		if rest == name {
+			continue
+		}
+		elementKeys := elementKeys(rest, field.ElementEnvNames)
+		switch len(elementKeys) {
+		case 0:
+		case 1:
+			keys = append(keys, strings.ToLower(elementKeys[0]))
+		default:
+			return nil, fmt.Errorf("variable %s is ambiguous, it belongs to elements %s", name, strings.Join(elementKeys, " and "))
+		}
+	}
+	return keys, nil
+}
+
+// elementKeys returns element keys variable name relative to collection variable can be split into. Names of
+// nested collections end with underscore and match as prefixes. Without known names key ends at the first underscore
+func elementKeys(rest string, elementNames []string) []string {
+	if elementNames == nil {
+		if i := strings.Index(rest, "_"); i > 0 {
+			return []string{rest[:i]}
+		}
+		return nil
+	}
+	seen := make(map[string]bool)
+	keys := make([]string, 0, 1)
+	for _, elementName := range elementNames {
+		if strings.HasSuffix(elementName, "_") {
+			for i := 1; i < len(rest); i++ {
+				if strings.HasPrefix(rest[i:], "_"+elementName) && !seen[rest[:i]] {
+					seen[rest[:i]] = true
+					keys = append(keys, rest[:i])
+				}
+			}
+			continue
+		}
+		for _, suffix := range []string{"_" + elementName, "_" + elementName + EnvFileSuffix} {
+			if key := strings.TrimSuffix(rest, suffix); key != rest && key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fileSource passed to Loader only describes the file, bound copy holds loaded data
+type fileSource struct {
+	path       func(p *processing) string
+	arg        string
+	loadedPath string
+	data       cfgfile.Data
+}
+
+func (s *fileSource) Name() string {
+	return "file"
+}
+
+func (s *fileSource) bind(p *processing) (Source, error) {
+	bound := &fileSource{path: s.path, arg: s.arg, loadedPath: s.path(p)}
+	if bound.loadedPath == "" {
+		return bound, nil
+	}
+	data, err := cfgfile.Load(bound.loadedPath)
+	if err != nil {
+		return nil, err
+	}
+	bound.data = data
+	return bound, nil
+}
+
+func (s *fileSource) Lookup(field *FieldInfo) (string, bool, error) {
+	value, found := s.data.Lookup(field.FileKeys, field.Delimiters, field.KeyValueSeparator)
+	return value, found, nil
+}
+
+// Keys discovers elements of the list or the mapping located by field file keys
+func (s *fileSource) Keys(field *FieldInfo) ([]string, error) {
+	return s.data.Keys(field.FileKeys), nil
+}
+
+// listDelimiter returns delimiter of the outermost level of collection field
+func listDelimiter(field *FieldInfo) string {
+	if len(field.Delimiters) == 0 {
+		return structs.DefaultDelimiters[0]
+	}
+	return field.Delimiters[0]
+}
+
+func configFilePath(p *processing) string {
+	if path := p.args.Lookup(ConfigFileArg); path != "" {
+		return path
+	}
+	path, _ := p.env.lookup(ConfigFileEnv)
+	return path
+}
@@ -0,0 +1,109 @@
+package configrant
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Usage writes plain-text table describing configuration fields: command line argument,
+// environment variable, type, default value and description (desc option)
+func Usage(cfg interface{}, w io.Writer) error {
+	return New().Usage(cfg, w)
+}
+
+// UsageMarkdown writes the same table as Usage in Markdown format
+func UsageMarkdown(cfg interface{}, w io.Writer) error {
+	return New().UsageMarkdown(cfg, w)
+}
+
+// Usage writes plain-text table describing configuration fields as they are seen by Loader,
+// so derived argument and environment variable names are included
+func (l *Loader) Usage(cfg interface{}, w io.Writer) error {
+	rows, err := l.usageRows(cfg)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(usageHeader, "\t"))
+	for _, row := range rows {
+		for i := range row {
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// UsageMarkdown writes the same table as Usage in Markdown format
+func (l *Loader) UsageMarkdown(cfg interface{}, w io.Writer) error {
+	rows, err := l.usageRows(cfg)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString("| " + strings.Join(usageHeader, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat(" --- |", len(usageHeader)) + "\n")
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "|", `\|`)
+			// everything except description is rendered as code
+			if row[i] != "" && i < len(row)-1 {
+				row[i] = "`" + row[i] + "`"
+			}
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+var usageHeader = []string{"FIELD", "ARG", "ENV", "TYPE", "DEFAULT", "DESCRIPTION"}
+
+func (l *Loader) usageRows(cfg interface{}) ([][]string, error) {
+	typ := reflect.TypeOf(cfg)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return nil, ErrNotPtrStruct
+	}
+	// usage is built on a zero copy, so passed configuration stays untouched
+	parser, err := l.parser(reflect.New(typ.Elem()).Interface())
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parser.Fields()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(fields))
+	for _, field := range fields {
+		desc := field.Description
+		if field.Required {
+			desc = strings.TrimSpace(desc + " (required)")
+		}
+		args := make([]string, 0, 2)
+		for _, name := range []string{field.ArgName, field.ShortArgName} {
+			if name != "" {
+				args = append(args, name)
+			}
+		}
+		if field.Positional {
+			args = append(args, "[args...]")
+		}
+		env := field.EnvVarName
+		if field.IsCollection() {
+			// element fields are listed under collection names with index or key placeholder
+			if len(args) > 0 {
+				args = []string{args[0] + ".<key>.*"}
+			}
+			if env != "" {
+				env += "_<KEY>_*"
+			}
+		}
+		rows = append(rows, []string{field.Path, strings.Join(args, ", "), env, field.Type.String(), field.Redact(field.DefaultValue), desc})
+	}
+	return rows, nil
+}
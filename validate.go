@@ -0,0 +1,42 @@
+package configrant
+
+import (
+	"sync"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// Validator checks field value referenced by validate option, value has the field type (pointers are dereferenced)
+type Validator = structs.Validator
+
+// ValidationError describes violated validation rule: min, max, oneof, pattern, name of the custom
+// validator or Validate for Validate method of the configuration struct. It matches ErrInvalid with errors.Is
+type ValidationError = structs.ValidationError
+
+// ErrInvalid is matched by every validation error
+var ErrInvalid = structs.ErrInvalid
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = make(map[string]Validator)
+)
+
+// RegisterValidator registers named validator for all loaders. Validator with the same name is replaced
+func RegisterValidator(name string, validator Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = validator
+}
+
+func (l *Loader) registeredValidators() map[string]Validator {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	registered := make(map[string]Validator, len(validators)+len(l.validators))
+	for name, validator := range validators {
+		registered[name] = validator
+	}
+	for name, validator := range l.validators {
+		registered[name] = validator
+	}
+	return registered
+}
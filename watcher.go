@@ -0,0 +1,248 @@
+package configrant
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/umalmyha/configrant/internal/structs"
+)
+
+// Change describes field which value has been changed on reload, values of secret fields are redacted
+type Change = structs.Change
+
+// WatchOption configures Watcher
+type WatchOption func(*Watcher)
+
+// WatchInterval sets how often configuration files are checked for changes, one second is used by default.
+// Interval must be positive, Watch fails otherwise
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WatchSignals sets signals which trigger reload, SIGHUP is used by default
+func WatchSignals(signals ...os.Signal) WatchOption {
+	return func(w *Watcher) {
+		w.signals = signals
+	}
+}
+
+// Watcher keeps configuration up to date: it reloads configuration when configuration file changes
+// or reload signal is received. Each reload populates a fresh copy of the configuration struct and
+// swaps it in atomically, so snapshot returned by Config is never modified afterwards
+type Watcher struct {
+	loader   *Loader
+	template reflect.Value
+	current  atomic.Value
+	interval time.Duration
+	signals  []os.Signal
+
+	mu        sync.Mutex
+	files     map[string]fileState
+	onChange  []func(cfg interface{}, changes []Change)
+	onError   []func(err error)
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch processes cfg with default Loader and starts watching for configuration changes
+func Watch(cfg interface{}, opts ...WatchOption) (*Watcher, error) {
+	return New().Watch(cfg, opts...)
+}
+
+// Watch processes cfg and starts watching for configuration changes. The initial
+// value of cfg (before processing) is used as a template for each reload
+func (l *Loader) Watch(cfg interface{}, opts ...WatchOption) (*Watcher, error) {
+	if _, err := structs.NewParser(cfg); err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		loader:   l,
+		template: structs.Clone(reflect.ValueOf(cfg).Elem()),
+		interval: time.Second,
+		signals:  []os.Signal{syscall.SIGHUP},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.interval <= 0 {
+		return nil, fmt.Errorf("configrant: watch interval must be positive, got %s", w.interval)
+	}
+	sources, err := l.process(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(cfg)
+	w.files = statFiles(sources)
+	go w.run()
+	return w, nil
+}
+
+// Config returns the current configuration snapshot, the pointer of the same type as passed to Watch.
+// Snapshot must be treated as read-only
+func (w *Watcher) Config() interface{} {
+	return w.current.Load()
+}
+
+// OnChange registers callback invoked after each reload which changed at least one field.
+// Callbacks are invoked synchronously with reload, so they must not call Reload
+func (w *Watcher) OnChange(fn func(cfg interface{}, changes []Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError registers callback invoked when background reload fails, the current snapshot is kept in this case
+func (w *Watcher) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// Reload populates a fresh copy of configuration and swaps it in. Change callbacks are invoked if any field
+// has been changed. If processing fails, error is returned and the current snapshot is kept
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reload()
+}
+
+// Close stops watching. Snapshot returned by Config stays available
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+	return nil
+}
+
+func (w *Watcher) reload() error {
+	fresh := reflect.New(w.template.Type())
+	fresh.Elem().Set(structs.Clone(w.template))
+	sources, err := w.loader.process(fresh.Interface(), nil)
+	// files state is refreshed even on failure, so broken file isn't reloaded over and over until it changes
+	if sources != nil {
+		w.files = statFiles(sources)
+	} else {
+		w.files = restatFiles(w.files)
+	}
+	if err != nil {
+		return err
+	}
+	from, err := w.loader.parser(w.current.Load())
+	if err != nil {
+		return err
+	}
+	to, err := w.loader.parser(fresh.Interface())
+	if err != nil {
+		return err
+	}
+	changes, err := structs.Diff(from, to)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	cfg := fresh.Interface()
+	w.current.Store(cfg)
+	for _, fn := range w.onChange {
+		fn(cfg, changes)
+	}
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	signals := make(chan os.Signal, 1)
+	if len(w.signals) > 0 {
+		signal.Notify(signals, w.signals...)
+		defer signal.Stop(signals)
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-signals:
+			w.backgroundReload(true)
+		case <-ticker.C:
+			w.backgroundReload(false)
+		}
+	}
+}
+
+func (w *Watcher) backgroundReload(force bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !force && !w.filesChanged() {
+		return
+	}
+	if err := w.reload(); err != nil {
+		for _, fn := range w.onError {
+			fn(err)
+		}
+	}
+}
+
+// statFiles records state of configuration files loaded by file sources bound to the last processing
+func statFiles(sources []Source) map[string]fileState {
+	paths := make([]string, 0)
+	for _, src := range sources {
+		if fs, ok := src.(*fileSource); ok && fs.loadedPath != "" {
+			paths = append(paths, fs.loadedPath)
+		}
+	}
+	return statPaths(paths)
+}
+
+func restatFiles(files map[string]fileState) map[string]fileState {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	return statPaths(paths)
+}
+
+func statPaths(paths []string) map[string]fileState {
+	files := make(map[string]fileState)
+	for _, path := range paths {
+		var state fileState
+		if info, err := os.Stat(path); err == nil {
+			state = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		files[path] = state
+	}
+	return files
+}
+
+func (w *Watcher) filesChanged() bool {
+	for path, state := range w.files {
+		var current fileState
+		if info, err := os.Stat(path); err == nil {
+			current = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		if !current.modTime.Equal(state.modTime) || current.size != state.size {
+			return true
+		}
+	}
+	return false
+}